@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+type ObjectEventType string
+
+// Events are published by the file API for the requests it serves. ObjectUploaded covers direct uploads and
+// completed multipart uploads; a single presigned PUT goes straight to S3 and does not emit one, consumers
+// that need those must subscribe to the bucket's S3 event notifications. ObjectDeleted is only published
+// when the object existed.
+const (
+	ObjectUploaded ObjectEventType = "ObjectUploaded"
+	ObjectDeleted  ObjectEventType = "ObjectDeleted"
+)
+
+type Identity struct {
+	Principal string `json:"principal,omitempty"`
+	SourceIp  string `json:"sourceIp,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+type ObjectEvent struct {
+	Type        ObjectEventType `json:"type"`
+	Bucket      string          `json:"bucket"`
+	Key         string          `json:"key"`
+	Size        int64           `json:"size"`
+	ContentType string          `json:"contentType,omitempty"`
+	RequestId   string          `json:"requestId,omitempty"`
+	Uploader    Identity        `json:"uploader"`
+	Time        time.Time       `json:"time"`
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event ObjectEvent) error
+}
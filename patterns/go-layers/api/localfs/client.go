@@ -0,0 +1,376 @@
+package localfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"dunno/api"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize    = 1000
+	DefaultContentType = "application/octet-stream"
+	tmpDir             = ".tmp"
+	metaDir            = ".meta"
+)
+
+type metadata struct {
+	ContentType string `json:"contentType"`
+	ETag        string `json:"etag"`
+}
+
+type fileReader struct {
+	io.Reader
+	io.Closer
+}
+
+type Client struct {
+	root     string
+	pageSize int
+}
+
+func New(root string, pageSize int) (*Client, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	err := os.MkdirAll(filepath.Join(root, tmpDir), 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		root:     root,
+		pageSize: pageSize,
+	}, nil
+}
+
+func (c *Client) bucketPath(bucket string) (string, error) {
+	if bucket == "" || strings.HasPrefix(bucket, ".") || strings.ContainsAny(bucket, `/\`) {
+		return "", fmt.Errorf("invalid bucket name %q", bucket)
+	}
+	return filepath.Join(c.root, bucket), nil
+}
+
+func (c *Client) objectPath(bucket, key string) (string, error) {
+	bucketPath, err := c.bucketPath(bucket)
+	if err != nil {
+		return "", err
+	}
+	if key == "" || strings.HasSuffix(key, "/") || path.Clean("/"+key) != "/"+key {
+		return "", fmt.Errorf("%w %q", api.InvalidKeyError, key)
+	}
+	return filepath.Join(bucketPath, filepath.FromSlash(key)), nil
+}
+
+func (c *Client) metadataPath(bucket, key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.root, metaDir, bucket, hex.EncodeToString(hash[:])+".json")
+}
+
+func (c *Client) readMetadata(bucket, key string) metadata {
+	meta := metadata{ContentType: DefaultContentType}
+	content, err := os.ReadFile(c.metadataPath(bucket, key))
+	if err == nil {
+		_ = json.Unmarshal(content, &meta)
+	}
+	return meta
+}
+
+func mapError(err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: %v", api.AccessDeniedError, err)
+	}
+	return err
+}
+
+func (c *Client) stat(ctx context.Context, bucket, key string) (string, os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	objectPath, err := c.objectPath(bucket, key)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := os.Stat(objectPath)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return "", nil, api.ObjectNotFoundError
+	}
+	if err != nil {
+		return "", nil, mapError(err)
+	}
+	return objectPath, info, nil
+}
+
+func (c *Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	objectPath, _, err := c.stat(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(objectPath)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return file, nil
+}
+
+func (c *Client) GetObjectRange(ctx context.Context, bucket, key string, start, end int64) (io.ReadCloser, error) {
+	objectPath, info, err := c.stat(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || end < start || start >= info.Size() {
+		return nil, fmt.Errorf("invalid range %d-%d for object of size %d", start, end, info.Size())
+	}
+	file, err := os.Open(objectPath)
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.Seek(start, io.SeekStart)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &fileReader{
+		Reader: io.LimitReader(file, end-start+1),
+		Closer: file,
+	}, nil
+}
+
+func (c *Client) HeadObject(ctx context.Context, bucket, key string) (*api.ObjectInfo, error) {
+	_, info, err := c.stat(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	meta := c.readMetadata(bucket, key)
+	return &api.ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ETag:         meta.ETag,
+		ContentType:  meta.ContentType,
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+func (c *Client) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	return c.PutObjectStream(ctx, bucket, key, bytes.NewReader(data), int64(len(data)), "")
+}
+
+func (c *Client) PutObjectStream(ctx context.Context,
+	bucket, key string,
+	body io.Reader,
+	size int64,
+	contentType string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectPath, err := c.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(objectPath), 0o755)
+	if err != nil {
+		return mapError(err)
+	}
+	tmp, err := os.CreateTemp(filepath.Join(c.root, tmpDir), "object-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("expected %d bytes, got %d", size, written)
+	}
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+	meta, err := json.Marshal(&metadata{
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(hash.Sum(nil)) + `"`,
+	})
+	if err != nil {
+		return err
+	}
+	metaPath := c.metadataPath(bucket, key)
+	err = os.MkdirAll(filepath.Dir(metaPath), 0o755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(metaPath, meta, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), objectPath)
+}
+
+func (c *Client) DeleteObject(ctx context.Context, bucket, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectPath, err := c.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(objectPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err = os.Remove(c.metadataPath(bucket, key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) CopyObject(ctx context.Context, bucket, sourceKey, destinationKey string) error {
+	source, err := c.GetObject(ctx, bucket, sourceKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+	meta := c.readMetadata(bucket, sourceKey)
+	return c.PutObjectStream(ctx, bucket, destinationKey, source, -1, meta.ContentType)
+}
+
+func (c *Client) keys(ctx context.Context, bucket string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	bucketPath, err := c.bucketPath(bucket)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	err = filepath.WalkDir(bucketPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == bucketPath {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(bucketPath, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (c *Client) ListObjects(ctx context.Context, bucket string, opts api.ListOptions) (*api.ListResult, error) {
+	keys, err := c.keys(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	start, _ := slices.BinarySearch(keys, opts.Prefix)
+	var after string
+	if opts.NextToken != nil {
+		decoded, err := base64.RawURLEncoding.DecodeString(*opts.NextToken)
+		if err != nil {
+			return nil, fmt.Errorf("%w: continuation token %q", api.InvalidArgumentError, *opts.NextToken)
+		}
+		after = string(decoded)
+		afterStart, _ := slices.BinarySearch(keys, after)
+		start = max(start, afterStart)
+	}
+	afterPrefix := opts.Delimiter != "" && len(after) > len(opts.Prefix) && strings.HasSuffix(after, opts.Delimiter)
+	result := &api.ListResult{
+		Objects:        make([]api.ObjectInfo, 0),
+		CommonPrefixes: make([]string, 0),
+	}
+	last := ""
+	count := 0
+	for _, key := range keys[start:] {
+		if !strings.HasPrefix(key, opts.Prefix) {
+			break
+		}
+		if key == after || (afterPrefix && strings.HasPrefix(key, after)) {
+			continue
+		}
+		entry := key
+		if opts.Delimiter != "" {
+			rest := key[len(opts.Prefix):]
+			if i := strings.Index(rest, opts.Delimiter); i >= 0 {
+				entry = opts.Prefix + rest[:i+len(opts.Delimiter)]
+			}
+		}
+		if entry == last {
+			continue
+		}
+		if count == c.pageSize {
+			token := base64.RawURLEncoding.EncodeToString([]byte(last))
+			result.NextToken = &token
+			break
+		}
+		if entry == key {
+			info, err := c.HeadObject(ctx, bucket, key)
+			if err != nil {
+				return nil, err
+			}
+			result.Objects = append(result.Objects, *info)
+		} else {
+			result.CommonPrefixes = append(result.CommonPrefixes, entry)
+		}
+		last = entry
+		count++
+	}
+	return result, nil
+}
+
+func (c *Client) PresignGetObject(context.Context, string, string, time.Duration) (*api.PresignedRequest, error) {
+	return nil, api.NotSupportedError
+}
+
+func (c *Client) PresignPutObject(context.Context, string, string, string, time.Duration) (*api.PresignedRequest, error) {
+	return nil, api.NotSupportedError
+}
+
+func (c *Client) CreateMultipartUpload(context.Context, string, string, string) (string, error) {
+	return "", api.NotSupportedError
+}
+
+func (c *Client) PresignUploadPart(context.Context, string, string, string, int32, time.Duration) (*api.PresignedRequest, error) {
+	return nil, api.NotSupportedError
+}
+
+func (c *Client) CompleteMultipartUpload(context.Context, string, string, string, []api.CompletedPart) error {
+	return api.NotSupportedError
+}
+
+func (c *Client) AbortMultipartUpload(context.Context, string, string, string) error {
+	return api.NotSupportedError
+}
@@ -0,0 +1,19 @@
+package localfs
+
+import (
+	"dunno/api"
+	"dunno/api/s3test"
+	"testing"
+)
+
+const smallPageSize = 7
+
+func TestConformance(t *testing.T) {
+	s3test.Run(t, func(t *testing.T) (api.S3Client, string) {
+		client, err := New(t.TempDir(), smallPageSize)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		return client, "bucket"
+	}, s3test.Options{})
+}
@@ -0,0 +1,39 @@
+package memevents
+
+import (
+	"context"
+	"dunno/api"
+	"slices"
+	"sync"
+)
+
+type Sink struct {
+	mutex  sync.Mutex
+	events []api.ObjectEvent
+}
+
+func New() *Sink {
+	return &Sink{}
+}
+
+func (s *Sink) Publish(ctx context.Context, event api.ObjectEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *Sink) Events() []api.ObjectEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return slices.Clone(s.events)
+}
+
+func (s *Sink) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = nil
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+type Factory func() (S3Client, error)
+
+type PublisherFactory func() (EventPublisher, error)
+
+type registry[F any] struct {
+	mutex     sync.RWMutex
+	kind      string
+	factories map[string]F
+}
+
+func newRegistry[F any](kind string) *registry[F] {
+	return &registry[F]{
+		kind:      kind,
+		factories: make(map[string]F),
+	}
+}
+
+func (r *registry[F]) register(name string, factory F) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.factories[name]; ok {
+		panic(fmt.Sprintf("%s implementation %q registered twice", r.kind, name))
+	}
+	r.factories[name] = factory
+}
+
+func (r *registry[F]) lookup(name string) (F, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	factory, ok := r.factories[name]
+	return factory, ok
+}
+
+func (r *registry[F]) names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+var (
+	clients    = newRegistry[Factory]("S3Client")
+	publishers = newRegistry[PublisherFactory]("EventPublisher")
+)
+
+func Register(name string, factory Factory) {
+	clients.register(name, factory)
+}
+
+func Lookup(name string) (Factory, bool) {
+	return clients.lookup(name)
+}
+
+func Registered() []string {
+	return clients.names()
+}
+
+func RegisterPublisher(name string, factory PublisherFactory) {
+	publishers.register(name, factory)
+}
+
+func LookupPublisher(name string) (PublisherFactory, bool) {
+	return publishers.lookup(name)
+}
+
+func RegisteredPublishers() []string {
+	return publishers.names()
+}
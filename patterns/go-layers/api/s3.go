@@ -4,12 +4,63 @@ import (
 	"context"
 	"errors"
 	"io"
+	"time"
 )
 
+const S3ClientInterfaceVersion = 4
+
 var ObjectNotFoundError = errors.New("object not found")
+var NotSupportedError = errors.New("operation not supported")
+var AccessDeniedError = errors.New("access denied")
+var ThrottledError = errors.New("request throttled")
+var InvalidKeyError = errors.New("invalid key")
+var InvalidArgumentError = errors.New("invalid argument")
+
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+type ListOptions struct {
+	Prefix    string
+	Delimiter string
+	NextToken *string
+}
+
+type ListResult struct {
+	Objects        []ObjectInfo
+	CommonPrefixes []string
+	NextToken      *string
+}
+
+type PresignedRequest struct {
+	URL       string              `json:"url"`
+	Method    string              `json:"method"`
+	Headers   map[string][]string `json:"headers,omitempty"`
+	ExpiresAt time.Time           `json:"expiresAt"`
+}
+
+type CompletedPart struct {
+	PartNumber int32  `json:"partNumber"`
+	ETag       string `json:"etag"`
+}
 
 type S3Client interface {
 	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	GetObjectRange(ctx context.Context, bucket, key string, start, end int64) (io.ReadCloser, error)
 	PutObject(ctx context.Context, bucket, key string, data []byte) error
-	ListObjects(ctx context.Context, bucket string, nextToken *string) ([]string, *string, error)
+	PutObjectStream(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string) error
+	HeadObject(ctx context.Context, bucket, key string) (*ObjectInfo, error)
+	DeleteObject(ctx context.Context, bucket, key string) error
+	CopyObject(ctx context.Context, bucket, sourceKey, destinationKey string) error
+	ListObjects(ctx context.Context, bucket string, opts ListOptions) (*ListResult, error)
+	PresignGetObject(ctx context.Context, bucket, key string, expires time.Duration) (*PresignedRequest, error)
+	PresignPutObject(ctx context.Context, bucket, key, contentType string, expires time.Duration) (*PresignedRequest, error)
+	CreateMultipartUpload(ctx context.Context, bucket, key, contentType string) (string, error)
+	PresignUploadPart(ctx context.Context, bucket, key, uploadId string, partNumber int32, expires time.Duration) (*PresignedRequest, error)
+	CompleteMultipartUpload(ctx context.Context, bucket, key, uploadId string, parts []CompletedPart) error
+	AbortMultipartUpload(ctx context.Context, bucket, key, uploadId string) error
 }
@@ -0,0 +1,319 @@
+package s3test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"dunno/api"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	DefaultManyKeys    = 1100
+	DefaultLargeObject = 8 << 20
+	maxListPages       = 10_000
+)
+
+type Factory func(t *testing.T) (client api.S3Client, bucket string)
+
+type Options struct {
+	ManyKeys        int
+	LargeObjectSize int
+}
+
+func Run(t *testing.T, factory Factory, opts Options) {
+	if opts.ManyKeys <= 0 {
+		opts.ManyKeys = DefaultManyKeys
+	}
+	if opts.LargeObjectSize <= 0 {
+		opts.LargeObjectSize = DefaultLargeObject
+	}
+	t.Run("PutGetRoundTrip", func(t *testing.T) {
+		client, bucket := factory(t)
+		objects := map[string][]byte{
+			"file.txt":          []byte("hello"),
+			"nested/dir/object": []byte("nested"),
+			"empty":             {},
+			"binary":            {0x00, 0xff, 0x10, 0x80},
+		}
+		for key, data := range objects {
+			putObject(t, client, bucket, key, data)
+		}
+		for key, data := range objects {
+			assertObject(t, client, bucket, key, data)
+		}
+	})
+	t.Run("Overwrite", func(t *testing.T) {
+		client, bucket := factory(t)
+		putObject(t, client, bucket, "key", []byte("first"))
+		putObject(t, client, bucket, "key", []byte("second"))
+		assertObject(t, client, bucket, "key", []byte("second"))
+	})
+	t.Run("LargeObject", func(t *testing.T) {
+		client, bucket := factory(t)
+		data := make([]byte, opts.LargeObjectSize)
+		_, _ = rand.Read(data)
+		putObject(t, client, bucket, "large", data)
+		assertObject(t, client, bucket, "large", data)
+	})
+	t.Run("MissingObject", func(t *testing.T) {
+		client, bucket := factory(t)
+		reader, err := client.GetObject(context.Background(), bucket, "missing")
+		if reader != nil {
+			_ = reader.Close()
+		}
+		if !errors.Is(err, api.ObjectNotFoundError) {
+			t.Fatalf("GetObject(missing) error = %v, want %v", err, api.ObjectNotFoundError)
+		}
+		if _, err = client.HeadObject(context.Background(), bucket, "missing"); !errors.Is(err, api.ObjectNotFoundError) {
+			t.Fatalf("HeadObject(missing) error = %v, want %v", err, api.ObjectNotFoundError)
+		}
+	})
+	t.Run("StreamWithContentType", func(t *testing.T) {
+		client, bucket := factory(t)
+		data := []byte("<html></html>")
+		err := client.PutObjectStream(context.Background(), bucket, "page.html", bytes.NewReader(data), int64(len(data)), "text/html")
+		if err != nil {
+			t.Fatalf("PutObjectStream() error = %v", err)
+		}
+		assertObject(t, client, bucket, "page.html", data)
+		info, err := client.HeadObject(context.Background(), bucket, "page.html")
+		if err != nil {
+			t.Fatalf("HeadObject() error = %v", err)
+		}
+		if info.Size != int64(len(data)) || info.ContentType != "text/html" || info.ETag == "" || info.LastModified.IsZero() {
+			t.Fatalf("HeadObject() = %+v, want size %d, content type text/html, ETag and last-modified set", info, len(data))
+		}
+	})
+	t.Run("Range", func(t *testing.T) {
+		client, bucket := factory(t)
+		putObject(t, client, bucket, "digits", []byte("0123456789"))
+		reader, err := client.GetObjectRange(context.Background(), bucket, "digits", 2, 5)
+		if err != nil {
+			t.Fatalf("GetObjectRange() error = %v", err)
+		}
+		defer func() {
+			_ = reader.Close()
+		}()
+		actual, err := io.ReadAll(reader)
+		if err != nil || string(actual) != "2345" {
+			t.Fatalf("GetObjectRange(2, 5) = %q, %v, want \"2345\"", actual, err)
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		client, bucket := factory(t)
+		putObject(t, client, bucket, "key", []byte("data"))
+		if err := client.DeleteObject(context.Background(), bucket, "key"); err != nil {
+			t.Fatalf("DeleteObject() error = %v", err)
+		}
+		if _, err := client.HeadObject(context.Background(), bucket, "key"); !errors.Is(err, api.ObjectNotFoundError) {
+			t.Fatalf("HeadObject() after delete error = %v, want %v", err, api.ObjectNotFoundError)
+		}
+		if err := client.DeleteObject(context.Background(), bucket, "key"); err != nil {
+			t.Fatalf("DeleteObject() of missing key error = %v, want nil", err)
+		}
+	})
+	t.Run("Copy", func(t *testing.T) {
+		client, bucket := factory(t)
+		data := []byte("copied")
+		err := client.PutObjectStream(context.Background(), bucket, "source", bytes.NewReader(data), int64(len(data)), "text/plain")
+		if err != nil {
+			t.Fatalf("PutObjectStream() error = %v", err)
+		}
+		if err = client.CopyObject(context.Background(), bucket, "source", "copy/of source"); err != nil {
+			t.Fatalf("CopyObject() error = %v", err)
+		}
+		assertObject(t, client, bucket, "copy/of source", data)
+		info, err := client.HeadObject(context.Background(), bucket, "copy/of source")
+		if err != nil || info.ContentType != "text/plain" {
+			t.Fatalf("HeadObject() of copy = %+v, %v, want content type text/plain", info, err)
+		}
+		err = client.CopyObject(context.Background(), bucket, "missing", "other")
+		if !errors.Is(err, api.ObjectNotFoundError) {
+			t.Fatalf("CopyObject(missing) error = %v, want %v", err, api.ObjectNotFoundError)
+		}
+	})
+	t.Run("Pagination", func(t *testing.T) {
+		client, bucket := factory(t)
+		expected := make(map[string]bool, opts.ManyKeys)
+		for i := 0; i < opts.ManyKeys; i++ {
+			key := fmt.Sprintf("many/%06d", i)
+			putObject(t, client, bucket, key, []byte(key))
+			expected[key] = false
+		}
+		var nextToken *string
+		for page := 0; ; page++ {
+			if page == maxListPages {
+				t.Fatalf("ListObjects did not terminate after %d pages", maxListPages)
+			}
+			result, err := client.ListObjects(context.Background(), bucket, api.ListOptions{NextToken: nextToken})
+			if err != nil {
+				t.Fatalf("ListObjects() error = %v", err)
+			}
+			for _, object := range result.Objects {
+				key := object.Key
+				seen, ok := expected[key]
+				if ok && seen {
+					t.Fatalf("ListObjects returned %s twice", key)
+				}
+				if ok {
+					expected[key] = true
+				}
+			}
+			token := result.NextToken
+			if token == nil {
+				break
+			}
+			if nextToken != nil && *token == *nextToken {
+				t.Fatalf("ListObjects returned the same continuation token twice")
+			}
+			nextToken = token
+		}
+		for key, seen := range expected {
+			if !seen {
+				t.Fatalf("ListObjects never returned %s", key)
+			}
+		}
+	})
+	t.Run("PrefixAndDelimiter", func(t *testing.T) {
+		client, bucket := factory(t)
+		for _, key := range []string{"a/b/one", "a/b/two", "a/b/c/three", "a/b/d/four", "a/bc", "a/other", "root"} {
+			putObject(t, client, bucket, key, []byte(key))
+		}
+		var keys, prefixes []string
+		var nextToken *string
+		for page := 0; ; page++ {
+			if page == maxListPages {
+				t.Fatalf("ListObjects did not terminate after %d pages", maxListPages)
+			}
+			result, err := client.ListObjects(context.Background(), bucket, api.ListOptions{
+				Prefix:    "a/b/",
+				Delimiter: "/",
+				NextToken: nextToken,
+			})
+			if err != nil {
+				t.Fatalf("ListObjects() error = %v", err)
+			}
+			for _, object := range result.Objects {
+				if object.Size != int64(len(object.Key)) || object.LastModified.IsZero() {
+					t.Errorf("ListObjects() object = %+v, want size %d and last-modified set", object, len(object.Key))
+				}
+				keys = append(keys, object.Key)
+			}
+			prefixes = append(prefixes, result.CommonPrefixes...)
+			if result.NextToken == nil {
+				break
+			}
+			nextToken = result.NextToken
+		}
+		slices.Sort(keys)
+		slices.Sort(prefixes)
+		if !slices.Equal(keys, []string{"a/b/one", "a/b/two"}) {
+			t.Errorf("ListObjects() keys = %v, want [a/b/one a/b/two]", keys)
+		}
+		if !slices.Equal(prefixes, []string{"a/b/c/", "a/b/d/"}) {
+			t.Errorf("ListObjects() common prefixes = %v, want [a/b/c/ a/b/d/]", prefixes)
+		}
+	})
+	t.Run("InvalidContinuationToken", func(t *testing.T) {
+		client, bucket := factory(t)
+		putObject(t, client, bucket, "key", []byte("data"))
+		_, err := client.ListObjects(context.Background(), bucket, api.ListOptions{NextToken: ptr("not a token!")})
+		if !errors.Is(err, api.InvalidArgumentError) {
+			t.Errorf("ListObjects() with invalid token error = %v, want %v", err, api.InvalidArgumentError)
+		}
+	})
+	t.Run("ContextCancellation", func(t *testing.T) {
+		client, bucket := factory(t)
+		putObject(t, client, bucket, "key", []byte("data"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := client.PutObject(ctx, bucket, "other", []byte("data")); !errors.Is(err, context.Canceled) {
+			t.Errorf("PutObject() with canceled context error = %v, want %v", err, context.Canceled)
+		}
+		reader, err := client.GetObject(ctx, bucket, "key")
+		if reader != nil {
+			_ = reader.Close()
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("GetObject() with canceled context error = %v, want %v", err, context.Canceled)
+		}
+		if _, err = client.ListObjects(ctx, bucket, api.ListOptions{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("ListObjects() with canceled context error = %v, want %v", err, context.Canceled)
+		}
+	})
+	t.Run("Presign", func(t *testing.T) {
+		client, bucket := factory(t)
+		putObject(t, client, bucket, "key", []byte("data"))
+		download, err := client.PresignGetObject(context.Background(), bucket, "key", time.Minute)
+		if errors.Is(err, api.NotSupportedError) {
+			t.Skip("presigning not supported")
+		}
+		if err != nil {
+			t.Fatalf("PresignGetObject() error = %v", err)
+		}
+		assertPresigned(t, download)
+		upload, err := client.PresignPutObject(context.Background(), bucket, "upload", "text/plain", time.Minute)
+		if err != nil {
+			t.Fatalf("PresignPutObject() error = %v", err)
+		}
+		assertPresigned(t, upload)
+		uploadId, err := client.CreateMultipartUpload(context.Background(), bucket, "multipart", "")
+		if err != nil {
+			t.Fatalf("CreateMultipartUpload() error = %v", err)
+		}
+		part, err := client.PresignUploadPart(context.Background(), bucket, "multipart", uploadId, 1, time.Minute)
+		if err != nil {
+			t.Fatalf("PresignUploadPart() error = %v", err)
+		}
+		assertPresigned(t, part)
+		if err = client.AbortMultipartUpload(context.Background(), bucket, "multipart", uploadId); err != nil {
+			t.Fatalf("AbortMultipartUpload() error = %v", err)
+		}
+	})
+}
+
+func assertPresigned(t *testing.T, request *api.PresignedRequest) {
+	t.Helper()
+	if request.URL == "" || request.Method == "" {
+		t.Fatalf("presigned request = %+v, want URL and method", request)
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		t.Errorf("presigned request expires at %v, want a future time", request.ExpiresAt)
+	}
+}
+
+func putObject(t *testing.T, client api.S3Client, bucket, key string, data []byte) {
+	t.Helper()
+	err := client.PutObject(context.Background(), bucket, key, data)
+	if err != nil {
+		t.Fatalf("PutObject(%s) error = %v", key, err)
+	}
+}
+
+func assertObject(t *testing.T, client api.S3Client, bucket, key string, expected []byte) {
+	t.Helper()
+	reader, err := client.GetObject(context.Background(), bucket, key)
+	if err != nil {
+		t.Fatalf("GetObject(%s) error = %v", key, err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	actual, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("reading %s failed: %v", key, err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Fatalf("GetObject(%s) returned %d bytes, want %d matching bytes", key, len(actual), len(expected))
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
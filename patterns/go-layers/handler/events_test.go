@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"dunno/api"
+	"dunno/api/localfs"
+	"dunno/api/memevents"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func usePublisher(t *testing.T) *memevents.Sink {
+	t.Helper()
+	sink := memevents.New()
+	previous := publisher
+	publisher = sink
+	t.Cleanup(func() {
+		publisher = previous
+	})
+	return sink
+}
+
+func deleteRequest(key string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodDelete,
+		Path:           "/" + key,
+		PathParameters: map[string]string{"proxy": key},
+	}
+}
+
+func TestDeletePublishesOnlyForExistingObjects(t *testing.T) {
+	t.Setenv("BUCKET_NAME", "bucket")
+	client, err := localfs.New(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("localfs.New() error = %v", err)
+	}
+	useClient(t, client)
+	sink := usePublisher(t)
+	if err = client.PutObject(context.Background(), "bucket", "file.txt", []byte("data")); err != nil {
+		t.Fatalf("PutObject() error = %v", err)
+	}
+
+	for _, key := range []string{"missing.txt", "file.txt", "file.txt"} {
+		response, err := handler(context.Background(), deleteRequest(key))
+		if err != nil || response.StatusCode != http.StatusNoContent {
+			t.Fatalf("DELETE %s = %d, %v, want %d", key, response.StatusCode, err, http.StatusNoContent)
+		}
+	}
+	published := sink.Events()
+	if len(published) != 1 {
+		t.Fatalf("published %d events, want 1: %+v", len(published), published)
+	}
+	if event := published[0]; event.Type != api.ObjectDeleted || event.Key != "file.txt" || event.Size != 4 {
+		t.Errorf("event = %+v, want ObjectDeleted for file.txt with size 4", event)
+	}
+}
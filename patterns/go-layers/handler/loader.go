@@ -0,0 +1,170 @@
+package main
+
+import (
+	"debug/buildinfo"
+	"dunno/api"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+)
+
+const (
+	ManifestFileName = "manifest.json"
+)
+
+var PluginNotFoundError = errors.New("plugin not found in manifest")
+var PluginsUnavailableError = errors.New("plugin loading is not available in this build")
+
+type PluginSpec struct {
+	Name             string            `json:"name"`
+	Path             string            `json:"path"`
+	Factory          string            `json:"factory"`
+	InterfaceVersion int               `json:"interfaceVersion"`
+	GoVersion        string            `json:"goVersion"`
+	Modules          map[string]string `json:"modules"`
+}
+
+type Manifest struct {
+	Plugins []PluginSpec `json:"plugins"`
+}
+
+type IncompatiblePluginError struct {
+	Name    string
+	Reasons []string
+}
+
+func (e *IncompatiblePluginError) Error() string {
+	return fmt.Sprintf("plugin %s is incompatible: %s", e.Name, strings.Join(e.Reasons, "; "))
+}
+
+type PluginLoader struct {
+	dir         string
+	manifest    Manifest
+	goVersion   string
+	hostModules map[string]string
+}
+
+func hostModules() map[string]string {
+	modules := make(map[string]string)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return modules
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		modules[dep.Path] = dep.Version
+	}
+	return modules
+}
+
+func NewPluginLoader(dir string) (*PluginLoader, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin manifest: %w", err)
+	}
+	var manifest Manifest
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plugin manifest: %w", err)
+	}
+	return &PluginLoader{
+		dir:         dir,
+		manifest:    manifest,
+		goVersion:   runtime.Version(),
+		hostModules: hostModules(),
+	}, nil
+}
+
+func (l *PluginLoader) Names() []string {
+	names := make([]string, 0, len(l.manifest.Plugins))
+	for _, spec := range l.manifest.Plugins {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+func (l *PluginLoader) find(name string) (*PluginSpec, error) {
+	if name == "" && len(l.manifest.Plugins) == 1 {
+		return &l.manifest.Plugins[0], nil
+	}
+	for i := range l.manifest.Plugins {
+		if l.manifest.Plugins[i].Name == name {
+			return &l.manifest.Plugins[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q, available: %s", PluginNotFoundError, name, strings.Join(l.Names(), ", "))
+}
+
+func (l *PluginLoader) check(spec *PluginSpec, path string) error {
+	var reasons []string
+	if spec.Factory == "" {
+		reasons = append(reasons, "factory symbol not declared")
+	}
+	if spec.InterfaceVersion != api.S3ClientInterfaceVersion {
+		reasons = append(reasons, fmt.Sprintf("interface version %d, handler expects %d",
+			spec.InterfaceVersion, api.S3ClientInterfaceVersion))
+	}
+	if spec.GoVersion != l.goVersion {
+		reasons = append(reasons, fmt.Sprintf("built with %s, handler built with %s", spec.GoVersion, l.goVersion))
+	}
+	modules := make([]string, 0, len(spec.Modules))
+	for module := range spec.Modules {
+		modules = append(modules, module)
+	}
+	slices.Sort(modules)
+	for _, module := range modules {
+		hostVersion, ok := l.hostModules[module]
+		if ok && hostVersion != spec.Modules[module] {
+			reasons = append(reasons, fmt.Sprintf("module %s %s, handler uses %s", module, spec.Modules[module], hostVersion))
+		}
+	}
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		reasons = append(reasons, fmt.Sprintf("failed to read build info of %s: %v", path, err))
+	} else if info.GoVersion != spec.GoVersion {
+		reasons = append(reasons, fmt.Sprintf("manifest declares %s but binary was built with %s", spec.GoVersion, info.GoVersion))
+	}
+	if len(reasons) > 0 {
+		return &IncompatiblePluginError{Name: spec.Name, Reasons: reasons}
+	}
+	return nil
+}
+
+func (l *PluginLoader) Load(name string) (api.S3Client, string, error) {
+	spec, err := l.find(name)
+	if err != nil {
+		return nil, "", err
+	}
+	path := spec.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.dir, path)
+	}
+	err = l.check(spec, path)
+	if err != nil {
+		return nil, "", err
+	}
+	symbol, err := openPlugin(path, spec.Factory)
+	if err != nil {
+		return nil, "", fmt.Errorf("plugin %s: %w", spec.Name, err)
+	}
+	factory, ok := symbol.(func() (api.S3Client, error))
+	if !ok {
+		return nil, "", &IncompatiblePluginError{
+			Name:    spec.Name,
+			Reasons: []string{fmt.Sprintf("symbol %s has type %T, expected func() (api.S3Client, error)", spec.Factory, symbol)},
+		}
+	}
+	client, err := factory()
+	if err != nil {
+		return nil, "", fmt.Errorf("plugin %s factory failed: %w", spec.Name, err)
+	}
+	return client, spec.Name, nil
+}
@@ -4,72 +4,123 @@ import (
 	"context"
 	"dunno/api"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
-	"plugin"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
+const DefaultContentType = "application/octet-stream"
+
 var s3Client api.S3Client
 
-const (
-	S3ClientFactoryName = "NewS3Client"
-)
+func getHeader(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
 
-func errorResponse(err error) (events.APIGatewayProxyResponse, error) {
-	return events.APIGatewayProxyResponse{}, err
+func decodedSize(body string) int64 {
+	padding := len(body) - len(strings.TrimRight(body, "="))
+	return int64(base64.StdEncoding.DecodedLen(len(body)) - padding)
 }
 
 func uploadFile(ctx context.Context,
 	bucket, key string,
 	request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	slog.Info("Uploading file to S3", "bucket", bucket, "key", key)
-	contentType, ok := request.Headers["Content-Type"]
-	if !ok {
-		return events.APIGatewayProxyResponse{
-			StatusCode:      http.StatusBadRequest,
-			IsBase64Encoded: false,
-			Body:            "Content-Type header not found",
-		}, nil
+	contentType := getHeader(request.Headers, "Content-Type")
+	if contentType == "" {
+		contentType = DefaultContentType
 	}
-	if contentType != "application/octet-stream" {
-		return events.APIGatewayProxyResponse{
-			StatusCode:      http.StatusBadRequest,
-			IsBase64Encoded: false,
-			Body:            "Content-Type header is not application/octet-stream",
-		}, nil
+	var body io.Reader = strings.NewReader(request.Body)
+	size := int64(len(request.Body))
+	if request.IsBase64Encoded {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+		size = decodedSize(request.Body)
 	}
-	reader := base64.NewDecoder(base64.StdEncoding, strings.NewReader(request.Body))
-	content, err := io.ReadAll(reader)
-	if err != nil {
-		return errorResponse(err)
-	}
-	err = s3Client.PutObject(ctx, bucket, key, content)
+	err := s3Client.PutObjectStream(ctx, bucket, key, body, size, contentType)
 	if err != nil {
 		return errorResponse(err)
 	}
+	publishEvent(ctx, request, api.ObjectEvent{
+		Type:        api.ObjectUploaded,
+		Bucket:      bucket,
+		Key:         key,
+		Size:        size,
+		ContentType: contentType,
+	})
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusAccepted,
 	}, nil
 }
 
-func downloadFile(ctx context.Context, bucket, key string) (events.APIGatewayProxyResponse, error) {
+func objectHeaders(info *api.ObjectInfo) map[string]string {
+	headers := map[string]string{
+		"Content-Type":  info.ContentType,
+		"Accept-Ranges": "bytes",
+	}
+	if info.ContentType == "" {
+		headers["Content-Type"] = DefaultContentType
+	}
+	if info.ETag != "" {
+		headers["ETag"] = info.ETag
+	}
+	if !info.LastModified.IsZero() {
+		headers["Last-Modified"] = info.LastModified.UTC().Format(http.TimeFormat)
+	}
+	return headers
+}
+
+func downloadFile(ctx context.Context,
+	bucket, key string,
+	request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	slog.Info("Downloading file from S3", "bucket", bucket, "key", key)
-	reader, err := s3Client.GetObject(ctx, bucket, key)
+	info, err := s3Client.HeadObject(ctx, bucket, key)
 	if err != nil {
-		if errors.Is(err, api.ObjectNotFoundError) {
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusNotFound,
-			}, nil
-		}
+		return errorResponse(err)
+	}
+	headers := objectHeaders(info)
+	statusCode := http.StatusOK
+	byteRange, err := parseRange(getHeader(request.Headers, "Range"), info.Size)
+	if errors.Is(err, RangeNotSatisfiableError) {
+		headers["Content-Range"] = fmt.Sprintf("bytes */%d", info.Size)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusRequestedRangeNotSatisfiable,
+			Headers:    headers,
+		}, nil
+	}
+	length := info.Size
+	if byteRange != nil {
+		statusCode = http.StatusPartialContent
+		length = byteRange.length()
+		headers["Content-Range"] = fmt.Sprintf("bytes %d-%d/%d", byteRange.start, byteRange.end, info.Size)
+	}
+	headers["Content-Length"] = strconv.FormatInt(length, 10)
+	if request.HTTPMethod == http.MethodHead || length == 0 {
+		return events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    headers,
+		}, nil
+	}
+	var reader io.ReadCloser
+	if byteRange != nil {
+		reader, err = s3Client.GetObjectRange(ctx, bucket, key, byteRange.start, byteRange.end)
+	} else {
+		reader, err = s3Client.GetObject(ctx, bucket, key)
+	}
+	if err != nil {
+		return errorResponse(err)
 	}
 	defer func(reader io.ReadCloser) {
 		_ = reader.Close()
@@ -78,96 +129,113 @@ func downloadFile(ctx context.Context, bucket, key string) (events.APIGatewayPro
 	if err != nil {
 		return errorResponse(err)
 	}
-	headers := map[string]string{
-		"Content-Type": "application/octet-stream",
-	}
 	return events.APIGatewayProxyResponse{
-		StatusCode:      200,
+		StatusCode:      statusCode,
 		Headers:         headers,
 		IsBase64Encoded: true,
 		Body:            base64.StdEncoding.EncodeToString(payload),
 	}, nil
 }
 
+func deleteFile(ctx context.Context,
+	bucket, key string,
+	request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	slog.Info("Deleting file from S3", "bucket", bucket, "key", key)
+	// DeleteObject succeeds for missing keys, so look the object up first and only report real deletions.
+	info, err := s3Client.HeadObject(ctx, bucket, key)
+	if errors.Is(err, api.ObjectNotFoundError) {
+		slog.Info("File already absent", "bucket", bucket, "key", key)
+		return statusResponse(http.StatusNoContent)
+	}
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = s3Client.DeleteObject(ctx, bucket, key)
+	if err != nil {
+		return errorResponse(err)
+	}
+	publishEvent(ctx, request, api.ObjectEvent{
+		Type:        api.ObjectDeleted,
+		Bucket:      bucket,
+		Key:         key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+	})
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
+
 type ListFilesResponse struct {
-	Files     []string `json:"files"`
-	NextToken *string  `json:"nextToken,omitempty"`
+	Prefix    string           `json:"prefix,omitempty"`
+	Folders   []string         `json:"folders"`
+	Files     []api.ObjectInfo `json:"files"`
+	NextToken *string          `json:"nextToken,omitempty"`
 }
 
 func listFiles(ctx context.Context, bucket string, queryParams map[string]string) (events.APIGatewayProxyResponse, error) {
-	slog.Info("Listing files from S3", "bucket", bucket)
-	var nextToken *string
+	opts := api.ListOptions{
+		Prefix:    queryParams["prefix"],
+		Delimiter: queryParams["delimiter"],
+	}
 	if value, ok := queryParams["nextToken"]; ok {
-		nextToken = &value
+		opts.NextToken = &value
 	}
-	result, next, err := s3Client.ListObjects(ctx, bucket, nextToken)
+	slog.Info("Listing files from S3", "bucket", bucket, "prefix", opts.Prefix, "delimiter", opts.Delimiter)
+	result, err := s3Client.ListObjects(ctx, bucket, opts)
 	if err != nil {
 		return errorResponse(err)
 	}
-	slog.Info(fmt.Sprintf("Fetched %d keys", len(result)))
+	slog.Info(fmt.Sprintf("Fetched %d keys and %d prefixes", len(result.Objects), len(result.CommonPrefixes)))
 	response := ListFilesResponse{
-		Files:     result,
-		NextToken: next,
+		Prefix:    opts.Prefix,
+		Folders:   result.CommonPrefixes,
+		Files:     result.Objects,
+		NextToken: result.NextToken,
 	}
-	body, err := json.Marshal(response)
-	if err != nil {
-		return errorResponse(err)
-	}
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body:            string(body),
-		IsBase64Encoded: false,
-	}, nil
+	return jsonResponse(http.StatusOK, response)
 }
 
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	filePath := request.PathParameters["proxy"]
 	bucket := os.Getenv("BUCKET_NAME")
-	switch request.HTTPMethod {
-	case "GET":
-		var response events.APIGatewayProxyResponse
-		var err error
-		if request.Path == "/" {
-			response, err = listFiles(ctx, bucket, request.QueryStringParameters)
-		} else {
-			response, err = downloadFile(ctx, bucket, filePath)
-		}
-		if err != nil {
-			slog.Error("Request failed", "path", request.Path, "method", request.HTTPMethod, "error", err.Error())
-			return errorResponse(err)
-		}
-		return response, nil
-	case "POST":
-		response, err := uploadFile(ctx, bucket, filePath, request)
-		if err != nil {
-			slog.Error("Request failed", "path", request.Path, "method", request.HTTPMethod, "error", err.Error())
-			return errorResponse(err)
-		}
-		return response, nil
-	}
-	return events.APIGatewayProxyResponse{
-		StatusCode: 400,
-	}, nil
-}
-
-func init() {
-	pluginPath := os.Getenv("PLUGIN_PATH")
-	p, err := plugin.Open(pluginPath)
-	if err != nil {
-		panic(err)
-	}
-	symbol, err := p.Lookup(S3ClientFactoryName)
-	if err != nil {
-		panic(err)
+	presign := request.QueryStringParameters["presign"]
+	uploadId := request.QueryStringParameters["uploadId"]
+	var response events.APIGatewayProxyResponse
+	var err error
+	switch {
+	case request.HTTPMethod == http.MethodGet && request.Path == HealthPath:
+		return health()
+	case s3Client == nil:
+		return clientError(http.StatusServiceUnavailable, activeErr.Error())
+	case request.HTTPMethod == http.MethodGet && request.Path == "/":
+		response, err = listFiles(ctx, bucket, request.QueryStringParameters)
+	case filePath == "":
+		return errorResponse(api.InvalidKeyError)
+	case request.HTTPMethod == http.MethodGet && presign == "download":
+		response, err = presignDownload(ctx, bucket, filePath)
+	case request.HTTPMethod == http.MethodPost && presign == "upload":
+		response, err = presignUpload(ctx, bucket, filePath, request)
+	case request.HTTPMethod == http.MethodPost && presign == "complete":
+		response, err = completeUpload(ctx, bucket, filePath, request)
+	case request.HTTPMethod == http.MethodDelete && uploadId != "":
+		response, err = abortUpload(ctx, bucket, filePath, uploadId)
+	case presign != "":
+		return clientError(http.StatusBadRequest, "unsupported presign operation: "+presign)
+	case request.HTTPMethod == http.MethodGet || request.HTTPMethod == http.MethodHead:
+		response, err = downloadFile(ctx, bucket, filePath, request)
+	case request.HTTPMethod == http.MethodPost:
+		response, err = uploadFile(ctx, bucket, filePath, request)
+	case request.HTTPMethod == http.MethodDelete:
+		response, err = deleteFile(ctx, bucket, filePath, request)
+	default:
+		return clientError(http.StatusMethodNotAllowed, "method not allowed: "+request.HTTPMethod)
 	}
-	client, err := symbol.(func() (api.S3Client, error))()
-	s3Client = client
 	if err != nil {
-		panic(err)
+		slog.Error("Request failed", "path", request.Path, "method", request.HTTPMethod, "error", err.Error())
+		return errorResponse(err)
 	}
+	return response, nil
 }
 
 func main() {
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"dunno/api"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type failingClient struct {
+	err error
+}
+
+func (c *failingClient) GetObject(context.Context, string, string) (io.ReadCloser, error) {
+	return nil, c.err
+}
+
+func (c *failingClient) GetObjectRange(context.Context, string, string, int64, int64) (io.ReadCloser, error) {
+	return nil, c.err
+}
+
+func (c *failingClient) PutObject(context.Context, string, string, []byte) error {
+	return c.err
+}
+
+func (c *failingClient) PutObjectStream(context.Context, string, string, io.Reader, int64, string) error {
+	return c.err
+}
+
+func (c *failingClient) HeadObject(context.Context, string, string) (*api.ObjectInfo, error) {
+	return nil, c.err
+}
+
+func (c *failingClient) DeleteObject(context.Context, string, string) error {
+	return c.err
+}
+
+func (c *failingClient) CopyObject(context.Context, string, string, string) error {
+	return c.err
+}
+
+func (c *failingClient) ListObjects(context.Context, string, api.ListOptions) (*api.ListResult, error) {
+	return nil, c.err
+}
+
+func (c *failingClient) PresignGetObject(context.Context, string, string, time.Duration) (*api.PresignedRequest, error) {
+	return nil, c.err
+}
+
+func (c *failingClient) PresignPutObject(context.Context, string, string, string, time.Duration) (*api.PresignedRequest, error) {
+	return nil, c.err
+}
+
+func (c *failingClient) CreateMultipartUpload(context.Context, string, string, string) (string, error) {
+	return "", c.err
+}
+
+func (c *failingClient) PresignUploadPart(context.Context, string, string, string, int32, time.Duration) (*api.PresignedRequest, error) {
+	return nil, c.err
+}
+
+func (c *failingClient) CompleteMultipartUpload(context.Context, string, string, string, []api.CompletedPart) error {
+	return c.err
+}
+
+func (c *failingClient) AbortMultipartUpload(context.Context, string, string, string) error {
+	return c.err
+}
+
+func useClient(t *testing.T, client api.S3Client) {
+	t.Helper()
+	previous := s3Client
+	s3Client = client
+	t.Cleanup(func() {
+		s3Client = previous
+	})
+}
+
+func TestHandlerMapsErrors(t *testing.T) {
+	const secret = "dynamo table arn:aws:secret"
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		message    string
+	}{
+		{"not found", fmt.Errorf("head: %w", api.ObjectNotFoundError), http.StatusNotFound, "head: object not found"},
+		{"access denied", fmt.Errorf("head: %w", api.AccessDeniedError), http.StatusForbidden, "head: access denied"},
+		{"throttled", fmt.Errorf("head: %w", api.ThrottledError), http.StatusTooManyRequests, "head: request throttled"},
+		{"invalid key", fmt.Errorf("head: %w", api.InvalidKeyError), http.StatusBadRequest, "head: invalid key"},
+		{"invalid argument", fmt.Errorf("list: %w", api.InvalidArgumentError), http.StatusBadRequest, "list: invalid argument"},
+		{"not supported", fmt.Errorf("head: %w", api.NotSupportedError), http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented)},
+		{"deadline", fmt.Errorf("%s: %w", secret, context.DeadlineExceeded), http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout)},
+		{"unknown", errors.New(secret), http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useClient(t, &failingClient{err: test.err})
+			response, err := handler(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodGet,
+				Path:           "/file.txt",
+				PathParameters: map[string]string{"proxy": "file.txt"},
+			})
+			if err != nil {
+				t.Fatalf("handler returned error: %v", err)
+			}
+			if response.StatusCode != test.statusCode {
+				t.Errorf("status = %d, want %d", response.StatusCode, test.statusCode)
+			}
+			if response.Headers["Content-Type"] != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", response.Headers["Content-Type"])
+			}
+			var body ErrorResponse
+			if err = json.Unmarshal([]byte(response.Body), &body); err != nil {
+				t.Fatalf("body %q is not JSON: %v", response.Body, err)
+			}
+			if body.Error != test.message {
+				t.Errorf("error = %q, want %q", body.Error, test.message)
+			}
+			retryAfter, ok := response.Headers["Retry-After"]
+			if wantRetry := test.statusCode == http.StatusTooManyRequests; ok != wantRetry || (ok && retryAfter != "1") {
+				t.Errorf("Retry-After = %q (present %v), want present %v", retryAfter, ok, wantRetry)
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsMissingKey(t *testing.T) {
+	useClient(t, &failingClient{})
+	response, err := handler(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/missing",
+	})
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestHandlerPresignNotSupported(t *testing.T) {
+	useClient(t, &failingClient{err: api.NotSupportedError})
+	response, err := handler(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod:            http.MethodGet,
+		Path:                  "/file.txt",
+		PathParameters:        map[string]string{"proxy": "file.txt"},
+		QueryStringParameters: map[string]string{"presign": "download"},
+	})
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if response.StatusCode != http.StatusNotImplemented {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !static
+
+package main
+
+import (
+	"fmt"
+	"plugin"
+)
+
+const PluginsSupported = true
+
+func openPlugin(path, symbol string) (any, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return p.Lookup(symbol)
+}
@@ -0,0 +1,14 @@
+//go:build static
+
+package main
+
+import (
+	_ "dunno/plugin/awsevents"
+	_ "dunno/plugin/awss3"
+)
+
+const PluginsSupported = false
+
+func openPlugin(string, string) (any, error) {
+	return nil, PluginsUnavailableError
+}
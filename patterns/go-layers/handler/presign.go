@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"dunno/api"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	DefaultPresignExpiry = 15 * time.Minute
+	MultipartThreshold   = 100 << 20
+	MinPartSize          = 64 << 20
+	MaxPartSize          = 5 << 30
+	MaxParts             = 1000
+)
+
+type MultipartUploadResponse struct {
+	UploadId  string                  `json:"uploadId"`
+	PartSize  int64                   `json:"partSize"`
+	Parts     []PresignedPartResponse `json:"parts"`
+	ExpiresAt time.Time               `json:"expiresAt"`
+}
+
+type PresignedPartResponse struct {
+	PartNumber int32 `json:"partNumber"`
+	api.PresignedRequest
+}
+
+type CompleteMultipartUploadRequest struct {
+	Parts []api.CompletedPart `json:"parts"`
+}
+
+func presignExpiry() time.Duration {
+	value := os.Getenv("PRESIGN_EXPIRY")
+	if value == "" {
+		return DefaultPresignExpiry
+	}
+	expiry, err := time.ParseDuration(value)
+	if err != nil || expiry <= 0 {
+		slog.Warn("Invalid PRESIGN_EXPIRY, using default", "value", value)
+		return DefaultPresignExpiry
+	}
+	return expiry
+}
+
+func presignDownload(ctx context.Context, bucket, key string) (events.APIGatewayProxyResponse, error) {
+	slog.Info("Presigning download", "bucket", bucket, "key", key)
+	_, err := s3Client.HeadObject(ctx, bucket, key)
+	if err != nil {
+		return errorResponse(err)
+	}
+	request, err := s3Client.PresignGetObject(ctx, bucket, key, presignExpiry())
+	if err != nil {
+		return errorResponse(err)
+	}
+	return jsonResponse(http.StatusOK, request)
+}
+
+func partSize(size int64) int64 {
+	partSize := int64(MinPartSize)
+	if minimum := (size + MaxParts - 1) / MaxParts; minimum > partSize {
+		partSize = minimum
+	}
+	return partSize
+}
+
+func presignMultipartUpload(ctx context.Context,
+	bucket, key, contentType string,
+	size int64) (events.APIGatewayProxyResponse, error) {
+	slog.Info("Initiating multipart upload", "bucket", bucket, "key", key, "size", size)
+	expiry := presignExpiry()
+	uploadId, err := s3Client.CreateMultipartUpload(ctx, bucket, key, contentType)
+	if err != nil {
+		return errorResponse(err)
+	}
+	response := MultipartUploadResponse{
+		UploadId:  uploadId,
+		PartSize:  partSize(size),
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	parts := int32((size + response.PartSize - 1) / response.PartSize)
+	for partNumber := int32(1); partNumber <= parts; partNumber++ {
+		request, err := s3Client.PresignUploadPart(ctx, bucket, key, uploadId, partNumber, expiry)
+		if err != nil {
+			_ = s3Client.AbortMultipartUpload(ctx, bucket, key, uploadId)
+			return errorResponse(err)
+		}
+		response.Parts = append(response.Parts, PresignedPartResponse{
+			PartNumber:       partNumber,
+			PresignedRequest: *request,
+		})
+	}
+	return jsonResponse(http.StatusOK, response)
+}
+
+func presignUpload(ctx context.Context,
+	bucket, key string,
+	request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	contentType := request.QueryStringParameters["contentType"]
+	var size int64
+	if value, ok := request.QueryStringParameters["size"]; ok {
+		var err error
+		size, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 0 || size > MaxPartSize*MaxParts {
+			return clientError(http.StatusBadRequest, "invalid size: "+value)
+		}
+	}
+	if size > MultipartThreshold {
+		return presignMultipartUpload(ctx, bucket, key, contentType, size)
+	}
+	// The client uploads to S3 directly, so no ObjectUploaded event is published for this upload.
+	slog.Info("Presigning upload", "bucket", bucket, "key", key)
+	presigned, err := s3Client.PresignPutObject(ctx, bucket, key, contentType, presignExpiry())
+	if err != nil {
+		return errorResponse(err)
+	}
+	return jsonResponse(http.StatusOK, presigned)
+}
+
+func completeUpload(ctx context.Context,
+	bucket, key string,
+	request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	uploadId := request.QueryStringParameters["uploadId"]
+	var body CompleteMultipartUploadRequest
+	err := json.Unmarshal([]byte(request.Body), &body)
+	if err != nil || uploadId == "" || len(body.Parts) == 0 {
+		return clientError(http.StatusBadRequest, "uploadId and a non-empty list of parts are required")
+	}
+	slog.Info("Completing multipart upload", "bucket", bucket, "key", key, "parts", len(body.Parts))
+	err = s3Client.CompleteMultipartUpload(ctx, bucket, key, uploadId, body.Parts)
+	if err != nil {
+		return errorResponse(err)
+	}
+	event := api.ObjectEvent{
+		Type:   api.ObjectUploaded,
+		Bucket: bucket,
+		Key:    key,
+	}
+	if info, err := s3Client.HeadObject(ctx, bucket, key); err == nil {
+		event.Size = info.Size
+		event.ContentType = info.ContentType
+	}
+	publishEvent(ctx, request, event)
+	return statusResponse(http.StatusCreated)
+}
+
+func abortUpload(ctx context.Context, bucket, key, uploadId string) (events.APIGatewayProxyResponse, error) {
+	slog.Info("Aborting multipart upload", "bucket", bucket, "key", key)
+	err := s3Client.AbortMultipartUpload(ctx, bucket, key, uploadId)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return statusResponse(http.StatusNoContent)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"dunno/api"
+	"dunno/api/localfs"
+	"dunno/api/memevents"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	LocalFsImplementation = "localfs"
+	DefaultImplementation = "aws"
+	HealthPath            = "/_health"
+	SourcePlugin          = "plugin"
+	SourceBuiltin         = "builtin"
+	localFsDefaultDir     = "dunno-storage"
+)
+
+var NoImplementationError = errors.New("no S3Client implementation available")
+
+type Implementation struct {
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Fallback string `json:"fallback,omitempty"`
+}
+
+type HealthResponse struct {
+	Status           string          `json:"status"`
+	Implementation   *Implementation `json:"implementation,omitempty"`
+	Registered       []string        `json:"registered"`
+	PluginsSupported bool            `json:"pluginsSupported"`
+	Publisher        string          `json:"publisher,omitempty"`
+	Error            string          `json:"error,omitempty"`
+	PublisherError   string          `json:"publisherError,omitempty"`
+}
+
+var active *Implementation
+var activeErr error
+var publisherErr error
+
+func loadPlugin(dir, name string) (api.S3Client, string, error) {
+	loader, err := NewPluginLoader(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	client, resolved, err := loader.Load(name)
+	if err != nil {
+		return nil, "", err
+	}
+	slog.Info("Plugin loaded", "name", resolved, "available", loader.Names())
+	return client, resolved, nil
+}
+
+func loadBuiltin(name string) (api.S3Client, error) {
+	factory, ok := api.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not compiled in, registered: %v", NoImplementationError, name, api.Registered())
+	}
+	return factory()
+}
+
+func pluginAvailable(dir string) bool {
+	if dir == "" || !PluginsSupported {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, ManifestFileName))
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+func selectClient() (api.S3Client, *Implementation, error) {
+	if os.Getenv("STORAGE_DIR") != "" {
+		client, err := loadBuiltin(LocalFsImplementation)
+		return client, &Implementation{Name: LocalFsImplementation, Source: SourceBuiltin}, err
+	}
+	name := os.Getenv("PLUGIN_NAME")
+	if dir := os.Getenv("PLUGIN_DIR"); pluginAvailable(dir) {
+		client, pluginName, err := loadPlugin(dir, name)
+		if err == nil {
+			return client, &Implementation{Name: pluginName, Source: SourcePlugin}, nil
+		}
+		slog.Warn("Plugin loading failed, falling back to builtin implementation", "dir", dir, "error", err.Error())
+	}
+	if name == "" {
+		name = DefaultImplementation
+	}
+	client, err := loadBuiltin(name)
+	if !errors.Is(err, NoImplementationError) {
+		return client, &Implementation{Name: name, Source: SourceBuiltin}, err
+	}
+	// localfs is compiled into every build, so a dynamic build whose plugin is missing still serves requests.
+	slog.Warn("Falling back to the local filesystem implementation", "error", err.Error())
+	client, fallbackErr := loadBuiltin(LocalFsImplementation)
+	if fallbackErr != nil {
+		return nil, nil, errors.Join(err, fallbackErr)
+	}
+	return client, &Implementation{Name: LocalFsImplementation, Source: SourceBuiltin, Fallback: err.Error()}, nil
+}
+
+func localFsRoot() string {
+	if dir := os.Getenv("STORAGE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), localFsDefaultDir)
+}
+
+func health() (events.APIGatewayProxyResponse, error) {
+	response := HealthResponse{
+		Status:           "ok",
+		Implementation:   active,
+		Registered:       api.Registered(),
+		PluginsSupported: PluginsSupported,
+		Publisher:        publisherName,
+	}
+	statusCode := http.StatusOK
+	if publisherErr != nil {
+		response.Status = "degraded"
+		response.PublisherError = publisherErr.Error()
+	}
+	if activeErr != nil {
+		response.Status = "unavailable"
+		response.Implementation = nil
+		response.Error = activeErr.Error()
+		statusCode = http.StatusServiceUnavailable
+	}
+	return jsonResponse(statusCode, response)
+}
+
+func init() {
+	api.Register(LocalFsImplementation, func() (api.S3Client, error) {
+		return localfs.New(localFsRoot(), 0)
+	})
+	api.RegisterPublisher(MemoryPublisher, func() (api.EventPublisher, error) {
+		return memevents.New(), nil
+	})
+	client, implementation, err := selectClient()
+	if err != nil {
+		slog.Error("No S3Client implementation available", "error", err.Error())
+		activeErr = err
+	} else {
+		slog.Info("Using S3Client implementation", "name", implementation.Name, "source", implementation.Source)
+		s3Client = client
+		active = implementation
+	}
+	publisher, publisherName, publisherErr = selectPublisher()
+	if publisherErr != nil {
+		slog.Error("Object events disabled", "error", publisherErr.Error())
+	}
+}
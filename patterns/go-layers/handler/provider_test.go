@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestSelectClientFallsBackToLocalFs(t *testing.T) {
+	t.Setenv("STORAGE_DIR", "")
+	t.Setenv("PLUGIN_DIR", "")
+	t.Setenv("PLUGIN_NAME", "missing")
+	t.Setenv("TMPDIR", t.TempDir())
+
+	client, implementation, err := selectClient()
+	if err != nil {
+		t.Fatalf("selectClient() error = %v", err)
+	}
+	if client == nil || implementation.Name != LocalFsImplementation || implementation.Fallback == "" {
+		t.Errorf("selectClient() = %v, %+v, want localfs fallback", client, implementation)
+	}
+}
+
+func useHealthState(t *testing.T, implementation *Implementation, clientErr, eventsErr error) {
+	t.Helper()
+	previousActive, previousErr, previousPublisherErr := active, activeErr, publisherErr
+	active, activeErr, publisherErr = implementation, clientErr, eventsErr
+	t.Cleanup(func() {
+		active, activeErr, publisherErr = previousActive, previousErr, previousPublisherErr
+	})
+}
+
+func TestHealthReportsClientAndPublisherErrors(t *testing.T) {
+	implementation := &Implementation{Name: LocalFsImplementation, Source: SourceBuiltin}
+	tests := []struct {
+		name           string
+		clientErr      error
+		eventsErr      error
+		statusCode     int
+		status         string
+		error          string
+		publisherError string
+	}{
+		{"healthy", nil, nil, http.StatusOK, "ok", "", ""},
+		{"publisher failed", nil, errors.New("no queue"), http.StatusOK, "degraded", "", "no queue"},
+		{"both failed", errors.New("no client"), errors.New("no queue"), http.StatusServiceUnavailable, "unavailable", "no client", "no queue"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useHealthState(t, implementation, test.clientErr, test.eventsErr)
+			response, err := health()
+			if err != nil || response.StatusCode != test.statusCode {
+				t.Fatalf("health() = %d, %v, want %d", response.StatusCode, err, test.statusCode)
+			}
+			var body HealthResponse
+			if err = json.Unmarshal([]byte(response.Body), &body); err != nil {
+				t.Fatalf("invalid health body %q: %v", response.Body, err)
+			}
+			if body.Status != test.status || body.Error != test.error || body.PublisherError != test.publisherError {
+				t.Errorf("health() body = %+v", body)
+			}
+		})
+	}
+}
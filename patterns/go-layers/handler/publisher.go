@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"dunno/api"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const MemoryPublisher = "memory"
+
+var publisher api.EventPublisher
+var publisherName string
+
+func selectPublisher() (api.EventPublisher, string, error) {
+	name := os.Getenv("EVENT_PUBLISHER")
+	if name == "" {
+		return nil, "", nil
+	}
+	factory, ok := api.LookupPublisher(name)
+	if !ok {
+		return nil, "", fmt.Errorf("event publisher %q is not available, registered: %v", name, api.RegisteredPublishers())
+	}
+	p, err := factory()
+	if err != nil {
+		return nil, "", fmt.Errorf("event publisher %s: %w", name, err)
+	}
+	return p, name, nil
+}
+
+func requestIdentity(request events.APIGatewayProxyRequest) api.Identity {
+	identity := api.Identity{
+		SourceIp:  request.RequestContext.Identity.SourceIP,
+		UserAgent: request.RequestContext.Identity.UserAgent,
+	}
+	authorizer := request.RequestContext.Authorizer
+	if claims, ok := authorizer["claims"].(map[string]any); ok {
+		identity.Principal, _ = claims["sub"].(string)
+	}
+	if principalId, ok := authorizer["principalId"].(string); ok && identity.Principal == "" {
+		identity.Principal = principalId
+	}
+	if identity.Principal == "" {
+		identity.Principal = request.RequestContext.Identity.UserArn
+	}
+	if identity.Principal == "" {
+		identity.Principal = request.RequestContext.Identity.User
+	}
+	return identity
+}
+
+func publishEvent(ctx context.Context, request events.APIGatewayProxyRequest, event api.ObjectEvent) {
+	if publisher == nil {
+		return
+	}
+	event.RequestId = request.RequestContext.RequestID
+	event.Uploader = requestIdentity(request)
+	event.Time = time.Now().UTC()
+	err := publisher.Publish(ctx, event)
+	if err != nil {
+		slog.Warn("Failed to publish object event", "type", event.Type, "key", event.Key, "error", err.Error())
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var RangeNotSatisfiableError = errors.New("range not satisfiable")
+
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+func (r byteRange) length() int64 {
+	return r.end - r.start + 1
+}
+
+func parseRange(header string, size int64) (*byteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, RangeNotSatisfiableError
+	}
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 || size == 0 {
+			return nil, RangeNotSatisfiableError
+		}
+		return &byteRange{start: max(size-suffix, 0), end: size - 1}, nil
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return nil, RangeNotSatisfiableError
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return nil, RangeNotSatisfiableError
+		}
+		end = min(end, size-1)
+	}
+	return &byteRange{start: start, end: end}, nil
+}
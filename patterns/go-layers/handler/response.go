@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"dunno/api"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func jsonResponse(statusCode int, body any) (events.APIGatewayProxyResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(payload),
+	}, nil
+}
+
+func statusResponse(statusCode int) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+	}, nil
+}
+
+func clientError(statusCode int, message string) (events.APIGatewayProxyResponse, error) {
+	return jsonResponse(statusCode, ErrorResponse{Error: message})
+}
+
+func errorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, api.ObjectNotFoundError):
+		return http.StatusNotFound
+	case errors.Is(err, api.AccessDeniedError):
+		return http.StatusForbidden
+	case errors.Is(err, api.ThrottledError):
+		return http.StatusTooManyRequests
+	case errors.Is(err, api.InvalidKeyError), errors.Is(err, api.InvalidArgumentError):
+		return http.StatusBadRequest
+	case errors.Is(err, api.NotSupportedError):
+		return http.StatusNotImplemented
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func errorResponse(err error) (events.APIGatewayProxyResponse, error) {
+	statusCode := errorStatusCode(err)
+	message := err.Error()
+	if statusCode >= http.StatusInternalServerError {
+		slog.Error("Request failed", "error", message)
+		message = http.StatusText(statusCode)
+	}
+	response, _ := clientError(statusCode, message)
+	if statusCode == http.StatusTooManyRequests {
+		response.Headers["Retry-After"] = "1"
+	}
+	return response, nil
+}
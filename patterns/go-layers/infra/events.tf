@@ -0,0 +1,25 @@
+resource "aws_sqs_queue" "events" {
+  name_prefix = "go-layers-events-"
+}
+
+data "aws_cloudwatch_event_bus" "default" {
+  name = "default"
+}
+
+data "aws_iam_policy_document" "events" {
+  statement {
+    effect    = "Allow"
+    actions   = ["sqs:SendMessage"]
+    resources = [aws_sqs_queue.events.arn]
+  }
+  statement {
+    effect    = "Allow"
+    actions   = ["events:PutEvents"]
+    resources = [data.aws_cloudwatch_event_bus.default.arn]
+  }
+}
+
+resource "aws_iam_role_policy" "events" {
+  policy = data.aws_iam_policy_document.events.json
+  role   = aws_iam_role.role.id
+}
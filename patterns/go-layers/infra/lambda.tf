@@ -19,6 +19,8 @@ data "aws_iam_policy_document" "s3" {
     actions = [
       "s3:GetObject",
       "s3:PutObject",
+      "s3:DeleteObject",
+      "s3:AbortMultipartUpload",
       "s3:ListBucket"
     ]
     resources = [
@@ -58,8 +60,13 @@ resource "aws_lambda_function" "lambda" {
   layers           = [aws_lambda_layer_version.plugin.arn]
   environment {
     variables = {
-      PLUGIN_PATH = "/opt/plugin-bin"
-      BUCKET_NAME = aws_s3_bucket.bucket.id
+      PLUGIN_DIR      = "/opt/plugins"
+      PLUGIN_NAME     = var.plugin_name
+      BUCKET_NAME     = aws_s3_bucket.bucket.id
+      PRESIGN_EXPIRY  = var.presign_expiry
+      EVENT_PUBLISHER = var.event_publisher
+      EVENT_QUEUE_URL = aws_sqs_queue.events.url
+      EVENT_BUS_NAME  = data.aws_cloudwatch_event_bus.default.name
     }
   }
 }
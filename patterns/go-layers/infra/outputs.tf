@@ -1,3 +1,8 @@
 output "api_id" {
   value = aws_apigatewayv2_api.api.id
 }
+
+
+output "events_queue_url" {
+  value = aws_sqs_queue.events.url
+}
\ No newline at end of file
@@ -5,3 +5,23 @@ variable "lambda_zip" {
 variable "plugin_zip" {
   type = string
 }
+
+variable "plugin_name" {
+  type    = string
+  default = "aws"
+}
+
+variable "presign_expiry" {
+  type    = string
+  default = "15m"
+}
+
+variable "event_publisher" {
+  type    = string
+  default = "sqs"
+
+  validation {
+    condition     = contains(["", "sqs", "eventbridge"], var.event_publisher)
+    error_message = "event_publisher must be one of: \"\", sqs, eventbridge."
+  }
+}
\ No newline at end of file
@@ -0,0 +1,110 @@
+package awsevents
+
+import (
+	"context"
+	"dunno/api"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+const (
+	SqsName         = "sqs"
+	EventBridgeName = "eventbridge"
+	EventSource     = "dunno.files"
+)
+
+type SqsPublisher struct {
+	client   *sqs.Client
+	queueUrl string
+}
+
+type EventBridgePublisher struct {
+	client  *eventbridge.Client
+	busName string
+}
+
+func init() {
+	api.RegisterPublisher(SqsName, func() (api.EventPublisher, error) {
+		return NewSqsPublisher(context.Background(), os.Getenv("EVENT_QUEUE_URL"))
+	})
+	api.RegisterPublisher(EventBridgeName, func() (api.EventPublisher, error) {
+		return NewEventBridgePublisher(context.Background(), os.Getenv("EVENT_BUS_NAME"))
+	})
+}
+
+func NewSqsPublisher(ctx context.Context, queueUrl string) (*SqsPublisher, error) {
+	if queueUrl == "" {
+		return nil, fmt.Errorf("SQS publisher requires a queue URL")
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &SqsPublisher{
+		client:   sqs.NewFromConfig(cfg),
+		queueUrl: queueUrl,
+	}, nil
+}
+
+func (p *SqsPublisher) Publish(ctx context.Context, event api.ObjectEvent) error {
+	body, err := json.Marshal(&event)
+	if err != nil {
+		return err
+	}
+	slog.Info("Sending object event to SQS", "type", event.Type, "key", event.Key)
+	_, err = p.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(p.queueUrl),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+func NewEventBridgePublisher(ctx context.Context, busName string) (*EventBridgePublisher, error) {
+	if busName == "" {
+		busName = "default"
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &EventBridgePublisher{
+		client:  eventbridge.NewFromConfig(cfg),
+		busName: busName,
+	}, nil
+}
+
+func (p *EventBridgePublisher) Publish(ctx context.Context, event api.ObjectEvent) error {
+	detail, err := json.Marshal(&event)
+	if err != nil {
+		return err
+	}
+	slog.Info("Putting object event to EventBridge", "type", event.Type, "key", event.Key)
+	out, err := p.client.PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{
+			{
+				EventBusName: aws.String(p.busName),
+				Source:       aws.String(EventSource),
+				DetailType:   aws.String(string(event.Type)),
+				Detail:       aws.String(string(detail)),
+				Resources:    []string{fmt.Sprintf("arn:aws:s3:::%s/%s", event.Bucket, event.Key)},
+				Time:         aws.Time(event.Time),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if out.FailedEntryCount > 0 {
+		entry := out.Entries[0]
+		return fmt.Errorf("EventBridge rejected event: %s: %s", aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage))
+	}
+	return nil
+}
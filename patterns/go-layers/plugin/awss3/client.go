@@ -0,0 +1,297 @@
+package awss3
+
+import (
+	"bytes"
+	"context"
+	"dunno/api"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const Name = "aws"
+
+type s3Client struct {
+	client    *s3.Client
+	presigner *s3.PresignClient
+}
+
+func init() {
+	api.Register(Name, New)
+}
+
+func New() (api.S3Client, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(cfg)
+	return &s3Client{
+		client:    client,
+		presigner: s3.NewPresignClient(client),
+	}, err
+}
+
+func mapError(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NotFound", "NoSuchUpload":
+			return api.ObjectNotFoundError
+		case "AccessDenied", "Forbidden", "AllAccessDisabled":
+			return api.AccessDeniedError
+		case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded", "TooManyRequests":
+			return api.ThrottledError
+		case "KeyTooLongError", "InvalidObjectName":
+			return api.InvalidKeyError
+		case "InvalidArgument":
+			return fmt.Errorf("%w: %s", api.InvalidArgumentError, apiErr.ErrorMessage())
+		}
+	}
+	return err
+}
+
+func (c *s3Client) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	slog.Info("PutObject called", "bucket", bucket, "key", key)
+	_, err := c.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return mapError(err)
+}
+
+func (c *s3Client) PutObjectStream(ctx context.Context,
+	bucket, key string,
+	body io.Reader,
+	size int64,
+	contentType string) error {
+	slog.Info("PutObjectStream called", "bucket", bucket, "key", key, "size", size)
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err := c.client.PutObject(ctx, input)
+	return mapError(err)
+}
+
+func (c *s3Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	slog.Info("GetObject called", "bucket", bucket, "key", key)
+	out, err := c.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return out.Body, nil
+}
+
+func (c *s3Client) GetObjectRange(ctx context.Context, bucket, key string, start, end int64) (io.ReadCloser, error) {
+	slog.Info("GetObjectRange called", "bucket", bucket, "key", key, "start", start, "end", end)
+	out, err := c.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return out.Body, nil
+}
+
+func (c *s3Client) HeadObject(ctx context.Context, bucket, key string) (*api.ObjectInfo, error) {
+	slog.Info("HeadObject called", "bucket", bucket, "key", key)
+	out, err := c.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &api.ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ETag:         aws.ToString(out.ETag),
+		ContentType:  aws.ToString(out.ContentType),
+		LastModified: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+func (c *s3Client) DeleteObject(ctx context.Context, bucket, key string) error {
+	slog.Info("DeleteObject called", "bucket", bucket, "key", key)
+	_, err := c.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return mapError(err)
+}
+
+func (c *s3Client) CopyObject(ctx context.Context, bucket, sourceKey, destinationKey string) error {
+	slog.Info("CopyObject called", "bucket", bucket, "sourceKey", sourceKey, "destinationKey", destinationKey)
+	segments := strings.Split(sourceKey, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	_, err := c.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(destinationKey),
+		CopySource: aws.String(bucket + "/" + strings.Join(segments, "/")),
+	})
+	return mapError(err)
+}
+
+func (c *s3Client) ListObjects(ctx context.Context, bucket string, opts api.ListOptions) (*api.ListResult, error) {
+	slog.Info("ListObjects called", "bucket", bucket, "prefix", opts.Prefix, "delimiter", opts.Delimiter, "nextToken", opts.NextToken)
+	input := &s3.ListObjectsV2Input{
+		Bucket:            aws.String(bucket),
+		ContinuationToken: opts.NextToken,
+	}
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	out, err := c.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	result := &api.ListResult{
+		Objects:        make([]api.ObjectInfo, 0, len(out.Contents)),
+		CommonPrefixes: make([]string, 0, len(out.CommonPrefixes)),
+		NextToken:      out.NextContinuationToken,
+	}
+	for _, obj := range out.Contents {
+		result.Objects = append(result.Objects, api.ObjectInfo{
+			Key:          aws.ToString(obj.Key),
+			Size:         aws.ToInt64(obj.Size),
+			ETag:         aws.ToString(obj.ETag),
+			LastModified: aws.ToTime(obj.LastModified),
+		})
+	}
+	for _, prefix := range out.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, aws.ToString(prefix.Prefix))
+	}
+	return result, nil
+}
+
+func toPresignedRequest(request *v4.PresignedHTTPRequest, expires time.Duration) *api.PresignedRequest {
+	return &api.PresignedRequest{
+		URL:       request.URL,
+		Method:    request.Method,
+		Headers:   request.SignedHeader,
+		ExpiresAt: time.Now().Add(expires),
+	}
+}
+
+func (c *s3Client) PresignGetObject(ctx context.Context,
+	bucket, key string,
+	expires time.Duration) (*api.PresignedRequest, error) {
+	slog.Info("PresignGetObject called", "bucket", bucket, "key", key)
+	request, err := c.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return toPresignedRequest(request, expires), nil
+}
+
+func (c *s3Client) PresignPutObject(ctx context.Context,
+	bucket, key, contentType string,
+	expires time.Duration) (*api.PresignedRequest, error) {
+	slog.Info("PresignPutObject called", "bucket", bucket, "key", key)
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	request, err := c.presigner.PresignPutObject(ctx, input, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return toPresignedRequest(request, expires), nil
+}
+
+func (c *s3Client) CreateMultipartUpload(ctx context.Context, bucket, key, contentType string) (string, error) {
+	slog.Info("CreateMultipartUpload called", "bucket", bucket, "key", key)
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	out, err := c.client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return "", mapError(err)
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+func (c *s3Client) PresignUploadPart(ctx context.Context,
+	bucket, key, uploadId string,
+	partNumber int32,
+	expires time.Duration) (*api.PresignedRequest, error) {
+	slog.Info("PresignUploadPart called", "bucket", bucket, "key", key, "partNumber", partNumber)
+	request, err := c.presigner.PresignUploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadId),
+		PartNumber: aws.Int32(partNumber),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return toPresignedRequest(request, expires), nil
+}
+
+func (c *s3Client) CompleteMultipartUpload(ctx context.Context,
+	bucket, key, uploadId string,
+	parts []api.CompletedPart) error {
+	slog.Info("CompleteMultipartUpload called", "bucket", bucket, "key", key, "parts", len(parts))
+	completed := make([]types.CompletedPart, len(parts))
+	for i, part := range parts {
+		completed[i] = types.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int32(part.PartNumber),
+		}
+	}
+	_, err := c.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadId),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	return mapError(err)
+}
+
+func (c *s3Client) AbortMultipartUpload(ctx context.Context, bucket, key, uploadId string) error {
+	slog.Info("AbortMultipartUpload called", "bucket", bucket, "key", key)
+	_, err := c.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadId),
+	})
+	return mapError(err)
+}
@@ -0,0 +1,14 @@
+#!/bin/sh -e
+# Usage: manifest.sh <name> <factory> <binary>
+name="$1"
+factory="$2"
+binary="$3"
+interface_version=$(sed -n 's/^const S3ClientInterfaceVersion = \([0-9]*\)$/\1/p' ../api/s3.go)
+go version -m "${binary}" | awk \
+    -v name="${name}" -v factory="${factory}" -v path="$(basename "${binary}")" -v iface="${interface_version}" '
+    NR == 1 { go = $2 }
+    $1 == "dep" { modules = modules sep "\"" $2 "\": \"" $3 "\""; sep = ", " }
+    END {
+        printf "{\"plugins\": [{\"name\": \"%s\", \"path\": \"%s\", \"factory\": \"%s\", ", name, path, factory
+        printf "\"interfaceVersion\": %s, \"goVersion\": \"%s\", \"modules\": {%s}}]}\n", iface, go, modules
+    }'
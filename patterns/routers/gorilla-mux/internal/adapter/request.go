@@ -0,0 +1,102 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type requestContextKey struct{}
+
+func NewContext(ctx context.Context, requestContext events.APIGatewayV2HTTPRequestContext) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, requestContext)
+}
+
+func FromContext(ctx context.Context) (events.APIGatewayV2HTTPRequestContext, bool) {
+	requestContext, ok := ctx.Value(requestContextKey{}).(events.APIGatewayV2HTTPRequestContext)
+	return requestContext, ok
+}
+
+func requestBody(request *events.APIGatewayV2HTTPRequest) ([]byte, error) {
+	if !request.IsBase64Encoded {
+		return []byte(request.Body), nil
+	}
+	body, err := base64.StdEncoding.DecodeString(request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 request body: %w", err)
+	}
+	return body, nil
+}
+
+func requestUrl(request *events.APIGatewayV2HTTPRequest, host string) (*url.URL, error) {
+	rawPath := request.RawPath
+	if rawPath == "" {
+		rawPath = request.RequestContext.HTTP.Path
+	}
+	if rawPath == "" {
+		rawPath = "/"
+	}
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request path %q: %w", rawPath, err)
+	}
+	requestUrl := &url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     path,
+		RawQuery: request.RawQueryString,
+	}
+	if path != rawPath {
+		requestUrl.RawPath = rawPath
+	}
+	return requestUrl, nil
+}
+
+func ToHttpRequest(ctx context.Context, request events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	method := request.RequestContext.HTTP.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	header := make(http.Header, len(request.Headers)+1)
+	for key, value := range request.Headers {
+		header.Set(key, value)
+	}
+	if len(request.Cookies) > 0 {
+		header.Set("Cookie", strings.Join(request.Cookies, "; "))
+	}
+	host := header.Get("Host")
+	if host == "" {
+		host = request.RequestContext.DomainName
+	}
+	requestUrl, err := requestUrl(&request, host)
+	if err != nil {
+		return nil, err
+	}
+	body, err := requestBody(&request)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(NewContext(ctx, request.RequestContext),
+		method,
+		requestUrl.String(),
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header = header
+	httpRequest.Host = host
+	httpRequest.RequestURI = requestUrl.RequestURI()
+	httpRequest.RemoteAddr = request.RequestContext.HTTP.SourceIP
+	if protocol := request.RequestContext.HTTP.Protocol; protocol != "" {
+		if major, minor, ok := http.ParseHTTPVersion(protocol); ok {
+			httpRequest.Proto, httpRequest.ProtoMajor, httpRequest.ProtoMinor = protocol, major, minor
+		}
+	}
+	return httpRequest, nil
+}
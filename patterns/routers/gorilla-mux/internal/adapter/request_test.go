@@ -0,0 +1,155 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const sampleEvent = `{
+  "version": "2.0",
+  "routeKey": "ANY /{proxy+}",
+  "rawPath": "/books/the%20hobbit/files/a%2Fb",
+  "rawQueryString": "author=tolkien&tag=fantasy&tag=classic&q=hello%20world",
+  "cookies": ["session=abc123", "theme=dark"],
+  "headers": {
+    "content-type": "application/json",
+    "date": "Mon, 02 Jan 2006 15:04:05 GMT",
+    "host": "books.example.com",
+    "user-agent": "Mozilla/5.0 (X11; Linux x86_64), curl/8.5.0",
+    "x-forwarded-for": "203.0.113.10"
+  },
+  "queryStringParameters": {"author": "tolkien", "tag": "fantasy,classic", "q": "hello world"},
+  "requestContext": {
+    "accountId": "123456789012",
+    "apiId": "api-id",
+    "domainName": "api-id.execute-api.eu-central-1.amazonaws.com",
+    "http": {
+      "method": "POST",
+      "path": "/books/the hobbit/files/a/b",
+      "protocol": "HTTP/1.1",
+      "sourceIp": "203.0.113.10",
+      "userAgent": "Mozilla/5.0 (X11; Linux x86_64), curl/8.5.0"
+    },
+    "requestId": "JKJaXmPLvHcESHA=",
+    "routeKey": "ANY /{proxy+}",
+    "stage": "$default",
+    "time": "02/Jan/2006:15:04:05 +0000",
+    "timeEpoch": 1136214245000
+  },
+  "body": "eyJ0aXRsZSI6IlRoZSBIb2JiaXQifQ==",
+  "isBase64Encoded": true
+}`
+
+func loadSampleEvent(t *testing.T) events.APIGatewayV2HTTPRequest {
+	t.Helper()
+	var request events.APIGatewayV2HTTPRequest
+	if err := json.Unmarshal([]byte(sampleEvent), &request); err != nil {
+		t.Fatalf("invalid sample event: %v", err)
+	}
+	return request
+}
+
+func TestToHttpRequest(t *testing.T) {
+	httpRequest, err := ToHttpRequest(context.Background(), loadSampleEvent(t))
+	if err != nil {
+		t.Fatalf("ToHttpRequest: %v", err)
+	}
+	if httpRequest.Method != http.MethodPost {
+		t.Errorf("method = %q, want POST", httpRequest.Method)
+	}
+	if got := httpRequest.URL.RawQuery; got != "author=tolkien&tag=fantasy&tag=classic&q=hello%20world" {
+		t.Errorf("raw query = %q", got)
+	}
+	if got := httpRequest.URL.Query()["tag"]; len(got) != 2 || got[0] != "fantasy" || got[1] != "classic" {
+		t.Errorf("tag values = %q", got)
+	}
+	if got := httpRequest.URL.Path; got != "/books/the hobbit/files/a/b" {
+		t.Errorf("path = %q", got)
+	}
+	if got := httpRequest.URL.EscapedPath(); got != "/books/the%20hobbit/files/a%2Fb" {
+		t.Errorf("escaped path = %q", got)
+	}
+	if got := httpRequest.RequestURI; got != "/books/the%20hobbit/files/a%2Fb?author=tolkien&tag=fantasy&tag=classic&q=hello%20world" {
+		t.Errorf("request URI = %q", got)
+	}
+	if httpRequest.Host != "books.example.com" {
+		t.Errorf("host = %q", httpRequest.Host)
+	}
+	body, err := io.ReadAll(httpRequest.Body)
+	if err != nil || string(body) != `{"title":"The Hobbit"}` {
+		t.Errorf("body = %q, %v", body, err)
+	}
+	if got := httpRequest.Header.Get("Cookie"); got != "session=abc123; theme=dark" {
+		t.Errorf("Cookie = %q", got)
+	}
+	if cookie, err := httpRequest.Cookie("theme"); err != nil || cookie.Value != "dark" {
+		t.Errorf("theme cookie = %v, %v", cookie, err)
+	}
+	for name, want := range map[string]string{
+		"User-Agent": "Mozilla/5.0 (X11; Linux x86_64), curl/8.5.0",
+		"Date":       "Mon, 02 Jan 2006 15:04:05 GMT",
+	} {
+		if got := httpRequest.Header.Values(name); len(got) != 1 || got[0] != want {
+			t.Errorf("%s = %q, want single value %q", name, got, want)
+		}
+	}
+	if httpRequest.RemoteAddr != "203.0.113.10" {
+		t.Errorf("remote addr = %q", httpRequest.RemoteAddr)
+	}
+	if httpRequest.Proto != "HTTP/1.1" || httpRequest.ProtoMajor != 1 || httpRequest.ProtoMinor != 1 {
+		t.Errorf("proto = %q %d.%d", httpRequest.Proto, httpRequest.ProtoMajor, httpRequest.ProtoMinor)
+	}
+	requestContext, ok := FromContext(httpRequest.Context())
+	if !ok || requestContext.RequestID != "JKJaXmPLvHcESHA=" || requestContext.Stage != "$default" {
+		t.Errorf("FromContext = %+v, %v", requestContext, ok)
+	}
+}
+
+func TestToHttpRequestDefaults(t *testing.T) {
+	request := events.APIGatewayV2HTTPRequest{
+		Body: "plain body",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			DomainName: "api-id.execute-api.eu-central-1.amazonaws.com",
+		},
+	}
+	httpRequest, err := ToHttpRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("ToHttpRequest: %v", err)
+	}
+	if httpRequest.Method != http.MethodGet || httpRequest.URL.Path != "/" {
+		t.Errorf("request = %s %s, want GET /", httpRequest.Method, httpRequest.URL.Path)
+	}
+	if httpRequest.Host != request.RequestContext.DomainName {
+		t.Errorf("host = %q, want domain name", httpRequest.Host)
+	}
+	body, _ := io.ReadAll(httpRequest.Body)
+	if string(body) != "plain body" {
+		t.Errorf("body = %q", body)
+	}
+	if _, ok := httpRequest.Header["Cookie"]; ok {
+		t.Error("unexpected Cookie header")
+	}
+}
+
+func TestToHttpRequestRejectsInvalidEvents(t *testing.T) {
+	tests := map[string]events.APIGatewayV2HTTPRequest{
+		"invalid base64 body": {RawPath: "/", Body: "not base64!", IsBase64Encoded: true},
+		"invalid path escape": {RawPath: "/books/%zz"},
+	}
+	for name, request := range tests {
+		if _, err := ToHttpRequest(context.Background(), request); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFromContextWithoutRequestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext reported a request context on an empty context")
+	}
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	PathTag     = "path"
+	QueryTag    = "query"
+	HeaderTag   = "header"
+	DefaultTag  = "default"
+	RequiredTag = "required"
+	MinTag      = "min"
+	MaxTag      = "max"
+)
+
+type BindingError struct {
+	Source string
+	Name   string
+	Reason string
+}
+
+func (e *BindingError) Error() string {
+	return fmt.Sprintf("invalid %s parameter %q: %s", e.Source, e.Name, e.Reason)
+}
+
+func paramSource(field reflect.StructField) (string, string, bool) {
+	for _, source := range []string{PathTag, QueryTag, HeaderTag} {
+		if name, ok := field.Tag.Lookup(source); ok {
+			return source, name, true
+		}
+	}
+	return "", "", false
+}
+
+func isRequired(field reflect.StructField) bool {
+	value, ok := field.Tag.Lookup(RequiredTag)
+	if !ok {
+		return false
+	}
+	required, err := strconv.ParseBool(value)
+	return err != nil || required
+}
+
+func paramValues(r *http.Request, source, name string) []string {
+	switch source {
+	case PathTag:
+		if value, ok := GetPathParams(r)[name]; ok {
+			return []string{value}
+		}
+	case QueryTag:
+		return r.URL.Query()[name]
+	case HeaderTag:
+		return r.Header.Values(name)
+	}
+	return nil
+}
+
+func checkBounds(field reflect.StructField, value float64) string {
+	if minimum, ok := field.Tag.Lookup(MinTag); ok {
+		bound, err := strconv.ParseFloat(minimum, 64)
+		if err == nil && value < bound {
+			return "must be at least " + minimum
+		}
+	}
+	if maximum, ok := field.Tag.Lookup(MaxTag); ok {
+		bound, err := strconv.ParseFloat(maximum, 64)
+		if err == nil && value > bound {
+			return "must be at most " + maximum
+		}
+	}
+	return ""
+}
+
+func setValue(field reflect.StructField, target reflect.Value, raw string) string {
+	switch target.Kind() {
+	case reflect.String:
+		if reason := checkBounds(field, float64(len(raw))); reason != "" {
+			return "length " + reason
+		}
+		target.SetString(raw)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "must be a boolean"
+		}
+		target.SetBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, target.Type().Bits())
+		if err != nil {
+			return "must be an integer"
+		}
+		if reason := checkBounds(field, float64(value)); reason != "" {
+			return reason
+		}
+		target.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(raw, 10, target.Type().Bits())
+		if err != nil {
+			return "must be a non-negative integer"
+		}
+		if reason := checkBounds(field, float64(value)); reason != "" {
+			return reason
+		}
+		target.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(raw, target.Type().Bits())
+		if err != nil {
+			return "must be a number"
+		}
+		if reason := checkBounds(field, value); reason != "" {
+			return reason
+		}
+		target.SetFloat(value)
+	default:
+		return fmt.Sprintf("unsupported parameter type %s", target.Type())
+	}
+	return ""
+}
+
+func bindField(field reflect.StructField, target reflect.Value, values []string) string {
+	switch target.Kind() {
+	case reflect.Pointer:
+		value := reflect.New(target.Type().Elem())
+		if reason := bindField(field, value.Elem(), values); reason != "" {
+			return reason
+		}
+		target.Set(value)
+		return ""
+	case reflect.Slice:
+		var items []string
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if reason := setValue(field, slice.Index(i), item); reason != "" {
+				return reason
+			}
+		}
+		target.Set(slice)
+		return ""
+	}
+	return setValue(field, target, values[0])
+}
+
+func BindParams(r *http.Request, params any) error {
+	value := reflect.ValueOf(params)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("params must be a pointer to a struct, got %T", params)
+	}
+	value = value.Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		source, name, ok := paramSource(field)
+		if !ok || !field.IsExported() {
+			continue
+		}
+		values := paramValues(r, source, name)
+		if len(values) == 0 || values[0] == "" {
+			if defaultValue, ok := field.Tag.Lookup(DefaultTag); ok {
+				values = []string{defaultValue}
+			} else if isRequired(field) {
+				return &BindingError{Source: source, Name: name, Reason: "is required"}
+			} else {
+				continue
+			}
+		}
+		if reason := bindField(field, value.Field(i), values); reason != "" {
+			return &BindingError{Source: source, Name: name, Reason: reason}
+		}
+	}
+	return nil
+}
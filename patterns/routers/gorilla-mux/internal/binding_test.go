@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type requiredParams struct {
+	Explicit string `query:"explicit" required:"true"`
+	Bare     string `query:"bare" required:""`
+	Optional string `query:"optional" required:"false"`
+}
+
+func TestBindParamsRequired(t *testing.T) {
+	tests := []struct {
+		query   string
+		missing string
+	}{
+		{"?explicit=a&bare=b", ""},
+		{"?bare=b", "explicit"},
+		{"?explicit=a", "bare"},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/"+test.query, nil)
+		var params requiredParams
+		err := BindParams(request, &params)
+		var bindingError *BindingError
+		switch {
+		case test.missing == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", test.query, err)
+		case test.missing != "" && (!errors.As(err, &bindingError) || bindingError.Name != test.missing):
+			t.Errorf("%s: error = %v, want %s required", test.query, err, test.missing)
+		}
+	}
+}
+
+func TestOpenApiParametersRequired(t *testing.T) {
+	generator := &schemaGenerator{schemas: make(map[string]*Schema)}
+	want := map[string]bool{"explicit": true, "bare": true, "optional": false}
+	for _, parameter := range generator.parameters(typeOf[requiredParams]()) {
+		if parameter.Required != want[parameter.Name] {
+			t.Errorf("%s required = %v, want %v", parameter.Name, parameter.Required, want[parameter.Name])
+		}
+	}
+}
+
+type pageParams struct {
+	Limit int32 `query:"limit" required:"true" min:"1"`
+}
+
+func TestParamsHandlerRejectsInvalidParams(t *testing.T) {
+	router := mux.NewRouter()
+	called := false
+	route := RegisterParamsFunc(router, "/pages", http.MethodGet, func(request *ParamsRequest[Unit, pageParams]) *Response[Unit] {
+		called = true
+		return &Response[Unit]{StatusCode: http.StatusNoContent}
+	})
+	tests := []struct {
+		query  string
+		reason string
+	}{
+		{"", "is required"},
+		{"?limit=many", "must be an integer"},
+		{"?limit=0", "must be at least 1"},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/pages"+test.query, nil)
+		request = WithQueryParams(WithPathParams(request, map[string]string{}), map[string]string{})
+		w := NewApiGwResponseWriter()
+		route.GetHandler().ServeHTTP(w, request)
+
+		response := w.GetResponse()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", test.query, response.StatusCode, http.StatusBadRequest)
+		}
+		var envelope ErrorEnvelope
+		if err := json.Unmarshal([]byte(response.Body), &envelope); err != nil {
+			t.Fatalf("%q: body %q is not an envelope: %v", test.query, response.Body, err)
+		}
+		if envelope.Code != "validation_failed" || envelope.Details["name"] != "limit" || envelope.Details["reason"] != test.reason {
+			t.Errorf("%q: envelope = %+v", test.query, envelope)
+		}
+	}
+	if called {
+		t.Error("handler called despite invalid params")
+	}
+}
@@ -1,7 +1,13 @@
 package internal
 
 import (
+	"context"
 	"dunno/internal/global"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
@@ -20,6 +26,12 @@ type BookResponse struct {
 	Pages   int      `json:"pages"`
 }
 
+func (BookResponse) DescribeHeaders() map[string]string {
+	return map[string]string{
+		ETagHeader: "Version of the book, for use in If-Match and If-None-Match",
+	}
+}
+
 type BookRequest struct {
 	Title   string   `json:"title"`
 	ISBN    string   `json:"isbn"`
@@ -27,11 +39,20 @@ type BookRequest struct {
 	Pages   int      `json:"pages"`
 }
 
+type BookPatchRequest struct {
+	Title   *string   `json:"title,omitempty"`
+	ISBN    *string   `json:"isbn,omitempty"`
+	Authors *[]string `json:"authors,omitempty"`
+	Pages   *int      `json:"pages,omitempty"`
+}
+
 type ListBooksResponse struct {
 	Books            []BookResponse `json:"books"`
 	LastEvaluatedKey *string        `json:"lastEvaluatedKey,omitempty"`
 }
 
+const MaxBookBodyBytes = 64 << 10
+
 const (
 	DynamoDbTag          = "dynamodb"
 	DynamoDbPartitionKey = "partitionKey"
@@ -43,20 +64,50 @@ type BookRecord struct {
 	ISBN    string
 	Authors []string
 	Pages   int
+	Version int64
 }
 
-func BooksKey(bookId string) map[string]types.AttributeValue {
+const BookVersionAttribute = "Version"
+
+func booksPartitionKeyName() string {
 	var record BookRecord
-	primaryKeyName := GetFieldsByTagValue(&record, DynamoDbTag, DynamoDbPartitionKey)[0].Name
+	return GetFieldsByTagValue(&record, DynamoDbTag, DynamoDbPartitionKey)[0].Name
+}
+
+func BooksKey(bookId string) map[string]types.AttributeValue {
 	return map[string]types.AttributeValue{
-		primaryKeyName: &types.AttributeValueMemberS{
+		booksPartitionKeyName(): &types.AttributeValueMemberS{
 			Value: bookId,
 		},
 	}
 }
 
-func GetBook(r *Request[Unit]) *Response[BookResponse] {
-	bookId := r.PathParams["id"]
+func toBookResponse(record BookRecord) *BookResponse {
+	return &BookResponse{
+		Id:      record.Id,
+		Title:   record.Title,
+		ISBN:    record.ISBN,
+		Authors: record.Authors,
+		Pages:   record.Pages,
+	}
+}
+
+func bookResponse(record BookRecord) *Response[BookResponse] {
+	return SuccessResponse(toBookResponse(record)).WithHeader(ETagHeader, FormatETag(record.Version))
+}
+
+type GetBookParams struct {
+	Id          string `path:"id" required:"true"`
+	IfNoneMatch string `header:"If-None-Match"`
+}
+
+type ConditionalBookParams struct {
+	Id      string `path:"id" required:"true"`
+	IfMatch string `header:"If-Match"`
+}
+
+func GetBook(r *ParamsRequest[Unit, GetBookParams]) *Response[BookResponse] {
+	bookId := r.Params.Id
 	global.Logger.Info("Fetching book with ID", zap.String("id", bookId))
 	out, err := global.DynamoDBClient.GetItem(r.Context, &dynamodb.GetItemInput{
 		TableName: aws.String(global.AppConfig.BooksTableArn),
@@ -65,7 +116,7 @@ func GetBook(r *Request[Unit]) *Response[BookResponse] {
 	if err != nil {
 		return ServerError[BookResponse](err)
 	}
-	if out.Item == nil {
+	if len(out.Item) == 0 {
 		return NotFoundResponse[BookResponse]()
 	}
 	var bookRecord BookRecord
@@ -73,13 +124,11 @@ func GetBook(r *Request[Unit]) *Response[BookResponse] {
 	if err != nil {
 		return ServerError[BookResponse](err)
 	}
-	return SuccessResponse(&BookResponse{
-		Id:      bookRecord.Id,
-		Title:   bookRecord.Title,
-		ISBN:    bookRecord.ISBN,
-		Authors: bookRecord.Authors,
-		Pages:   bookRecord.Pages,
-	})
+	etag := FormatETag(bookRecord.Version)
+	if r.Params.IfNoneMatch != "" && ETagMatches(r.Params.IfNoneMatch, etag, true) {
+		return NotModifiedResponse[BookResponse]().WithHeader(ETagHeader, etag)
+	}
+	return bookResponse(bookRecord)
 }
 
 func SaveBook(r *Request[BookRequest]) *Response[BookResponse] {
@@ -90,52 +139,198 @@ func SaveBook(r *Request[BookRequest]) *Response[BookResponse] {
 		ISBN:    r.Body.ISBN,
 		Authors: r.Body.Authors,
 		Pages:   r.Body.Pages,
+		Version: 1,
 	}
 	av, err := attributevalue.MarshalMap(record)
 	if err != nil {
 		return ServerError[BookResponse](err)
 	}
 	_, err = global.DynamoDBClient.PutItem(r.Context, &dynamodb.PutItemInput{
-		TableName: aws.String(global.AppConfig.BooksTableArn),
-		Item:      av,
+		TableName:                aws.String(global.AppConfig.BooksTableArn),
+		Item:                     av,
+		ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+		ExpressionAttributeNames: map[string]string{"#id": booksPartitionKeyName()},
 	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrorResponse[BookResponse](NewApiError(ConflictError, "book already exists").WithCause(err))
+	}
 	if err != nil {
 		return ServerError[BookResponse](err)
 	}
-	return SuccessResponse(&BookResponse{
-		Id:      record.Id,
-		Title:   record.Title,
-		ISBN:    record.ISBN,
-		Authors: record.Authors,
-		Pages:   record.Pages,
-	})
+	return bookResponse(record)
 }
 
-const (
-	lastEvaluatedKeyQueryParam = "LastEvaluatedKey"
-	limitQueryParam            = "limit"
-)
+type conditionalWrite struct {
+	condition string
+	names     map[string]string
+	values    map[string]types.AttributeValue
+}
 
-func ListBooks(r *Request[Unit]) *Response[ListBooksResponse] {
-	encodedLastEvaluatedKey := GetQueryParam(r, lastEvaluatedKeyQueryParam)
-	var limit *int32
-	var err error
-	limitStr := GetQueryParam(r, limitQueryParam)
-	if limitStr != "" {
-		limit, err = ParseInt32(limitStr)
+func newConditionalWrite(ifMatch string) (*conditionalWrite, error) {
+	write := &conditionalWrite{
+		condition: "attribute_exists(#id)",
+		names:     map[string]string{"#id": booksPartitionKeyName()},
+		values:    make(map[string]types.AttributeValue),
+	}
+	if ifMatch == "" {
+		return write, nil
+	}
+	versions, wildcard := ParseVersionETags(ifMatch)
+	if wildcard {
+		return write, nil
+	}
+	if len(versions) == 0 {
+		return nil, NewApiError(PreconditionFailedError, "If-Match does not match any version of the book")
+	}
+	write.names["#version"] = BookVersionAttribute
+	var clauses []string
+	for i, version := range versions {
+		if version == 0 {
+			clauses = append(clauses, "attribute_not_exists(#version)")
+			continue
+		}
+		placeholder := fmt.Sprintf(":version%d", i)
+		write.values[placeholder] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+		clauses = append(clauses, "#version = "+placeholder)
+	}
+	write.condition += " AND (" + strings.Join(clauses, " OR ") + ")"
+	return write, nil
+}
+
+func conditionalWriteError(err error) error {
+	var conditionFailed *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionFailed) {
+		return err
+	}
+	if len(conditionFailed.Item) == 0 {
+		return NotFoundError
+	}
+	var current BookRecord
+	if unmarshalErr := attributevalue.UnmarshalMap(conditionFailed.Item, &current); unmarshalErr != nil {
+		return unmarshalErr
+	}
+	return NewApiError(PreconditionFailedError, "book has been modified").WithDetails(map[string]any{
+		"etag": FormatETag(current.Version),
+	}).WithCause(err)
+}
+
+func updateBook(ctx context.Context, bookId, ifMatch string, fields map[string]any) *Response[BookResponse] {
+	if len(fields) == 0 {
+		return ErrorResponse[BookResponse](NewApiError(ValidationError, "no fields to update"))
+	}
+	write, err := newConditionalWrite(ifMatch)
+	if err != nil {
+		return ErrorResponse[BookResponse](err)
+	}
+	attributes := make([]string, 0, len(fields))
+	for attribute := range fields {
+		attributes = append(attributes, attribute)
+	}
+	slices.Sort(attributes)
+	assignments := []string{"#version = if_not_exists(#version, :zero) + :one"}
+	write.names["#version"] = BookVersionAttribute
+	write.values[":zero"] = &types.AttributeValueMemberN{Value: "0"}
+	write.values[":one"] = &types.AttributeValueMemberN{Value: "1"}
+	for i, attribute := range attributes {
+		value, err := attributevalue.Marshal(fields[attribute])
 		if err != nil {
-			return ServerError[ListBooksResponse](err)
+			return ServerError[BookResponse](err)
 		}
+		name, placeholder := fmt.Sprintf("#field%d", i), fmt.Sprintf(":field%d", i)
+		write.names[name] = attribute
+		write.values[placeholder] = value
+		assignments = append(assignments, name+" = "+placeholder)
+	}
+	out, err := global.DynamoDBClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                           aws.String(global.AppConfig.BooksTableArn),
+		Key:                                 BooksKey(bookId),
+		UpdateExpression:                    aws.String("SET " + strings.Join(assignments, ", ")),
+		ConditionExpression:                 aws.String(write.condition),
+		ExpressionAttributeNames:            write.names,
+		ExpressionAttributeValues:           write.values,
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		return ErrorResponse[BookResponse](conditionalWriteError(err))
 	}
+	var record BookRecord
+	err = attributevalue.UnmarshalMap(out.Attributes, &record)
+	if err != nil {
+		return ServerError[BookResponse](err)
+	}
+	return bookResponse(record)
+}
+
+func UpdateBook(r *ParamsRequest[BookRequest, ConditionalBookParams]) *Response[BookResponse] {
+	global.Logger.Infow("Replacing book", "id", r.Params.Id)
+	return updateBook(r.Context, r.Params.Id, r.Params.IfMatch, map[string]any{
+		"Title":   r.Body.Title,
+		"ISBN":    r.Body.ISBN,
+		"Authors": r.Body.Authors,
+		"Pages":   r.Body.Pages,
+	})
+}
+
+func PatchBook(r *ParamsRequest[BookPatchRequest, ConditionalBookParams]) *Response[BookResponse] {
+	global.Logger.Infow("Patching book", "id", r.Params.Id)
+	fields := make(map[string]any)
+	if r.Body.Title != nil {
+		fields["Title"] = *r.Body.Title
+	}
+	if r.Body.ISBN != nil {
+		fields["ISBN"] = *r.Body.ISBN
+	}
+	if r.Body.Authors != nil {
+		fields["Authors"] = *r.Body.Authors
+	}
+	if r.Body.Pages != nil {
+		fields["Pages"] = *r.Body.Pages
+	}
+	return updateBook(r.Context, r.Params.Id, r.Params.IfMatch, fields)
+}
+
+func DeleteBook(r *ParamsRequest[Unit, ConditionalBookParams]) *Response[Unit] {
+	global.Logger.Infow("Deleting book", "id", r.Params.Id)
+	write, err := newConditionalWrite(r.Params.IfMatch)
+	if err != nil {
+		return ErrorResponse[Unit](err)
+	}
+	var values map[string]types.AttributeValue
+	if len(write.values) > 0 {
+		values = write.values
+	}
+	_, err = global.DynamoDBClient.DeleteItem(r.Context, &dynamodb.DeleteItemInput{
+		TableName:                           aws.String(global.AppConfig.BooksTableArn),
+		Key:                                 BooksKey(r.Params.Id),
+		ConditionExpression:                 aws.String(write.condition),
+		ExpressionAttributeNames:            write.names,
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		return ErrorResponse[Unit](conditionalWriteError(err))
+	}
+	return SuccessResponse(&Unit{})
+}
+
+type ListBooksParams struct {
+	Limit            int32  `query:"limit" default:"20" min:"1" max:"100"`
+	LastEvaluatedKey string `query:"LastEvaluatedKey"`
+}
+
+func ListBooks(r *ParamsRequest[Unit, ListBooksParams]) *Response[ListBooksResponse] {
 	var lastEvaluatedKey map[string]types.AttributeValue
-	if encodedLastEvaluatedKey != "" {
-		lastEvaluatedKey, err = DecodeLastEvaluatedKey(encodedLastEvaluatedKey)
+	var err error
+	if r.Params.LastEvaluatedKey != "" {
+		lastEvaluatedKey, err = DecodeLastEvaluatedKey(r.Params.LastEvaluatedKey)
 		if err != nil {
-			return ServerError[ListBooksResponse](err)
+			return ErrorResponse[ListBooksResponse](NewApiError(ValidationError, "invalid LastEvaluatedKey").WithCause(err))
 		}
 	}
 	out, err := global.DynamoDBClient.Scan(r.Context, &dynamodb.ScanInput{
-		Limit:             limit,
+		Limit:             aws.Int32(r.Params.Limit),
 		TableName:         aws.String(global.AppConfig.BooksTableArn),
 		ExclusiveStartKey: lastEvaluatedKey,
 	})
@@ -173,8 +368,10 @@ func ListBooks(r *Request[Unit]) *Response[ListBooksResponse] {
 }
 
 func init() {
-	RegisterFunc(global.Router, "/books/{id}", "GET", GetBook)
-	RegisterFunc(global.Router, "/books", "GET", ListBooks)
-	RegisterFunc(global.Router, "/books", "POST", SaveBook)
-	RegisterFuncMatchContentType(global.Router, "/books", "POST", SaveBook, "application/json")
+	RegisterParamsFunc(global.Router, "/books/{id}", "GET", GetBook)
+	RegisterParamsFunc(global.Router, "/books/{id}", "PUT", UpdateBook, BodyLimitMiddleware(MaxBookBodyBytes))
+	RegisterParamsFunc(global.Router, "/books/{id}", "PATCH", PatchBook, BodyLimitMiddleware(MaxBookBodyBytes))
+	RegisterParamsFunc(global.Router, "/books/{id}", "DELETE", DeleteBook)
+	RegisterParamsFunc(global.Router, "/books", "GET", ListBooks)
+	DocumentErrors(RegisterFunc(global.Router, "/books", "POST", SaveBook, BodyLimitMiddleware(MaxBookBodyBytes)), ConflictError)
 }
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"context"
+	"dunno/internal/global"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestNewConditionalWrite(t *testing.T) {
+	tests := []struct {
+		ifMatch   string
+		condition string
+		values    []string
+	}{
+		{"", "attribute_exists(#id)", nil},
+		{"*", "attribute_exists(#id)", nil},
+		{`"3"`, "attribute_exists(#id) AND (#version = :version0)", []string{":version0"}},
+		{`"0"`, "attribute_exists(#id) AND (attribute_not_exists(#version))", nil},
+		{`"0", "2"`, "attribute_exists(#id) AND (attribute_not_exists(#version) OR #version = :version1)", []string{":version1"}},
+	}
+	for _, test := range tests {
+		write, err := newConditionalWrite(test.ifMatch)
+		if err != nil {
+			t.Errorf("%q: %v", test.ifMatch, err)
+			continue
+		}
+		if write.condition != test.condition {
+			t.Errorf("%q: condition = %q, want %q", test.ifMatch, write.condition, test.condition)
+		}
+		if len(write.values) != len(test.values) {
+			t.Errorf("%q: values = %v, want %v", test.ifMatch, write.values, test.values)
+		}
+		for _, placeholder := range test.values {
+			if _, ok := write.values[placeholder]; !ok {
+				t.Errorf("%q: missing value %s", test.ifMatch, placeholder)
+			}
+		}
+	}
+
+	_, err := newConditionalWrite(`W/"3"`)
+	if !errors.Is(err, PreconditionFailedError) {
+		t.Errorf("weak If-Match: err = %v, want precondition failed", err)
+	}
+}
+
+func TestConditionalWriteError(t *testing.T) {
+	other := errors.New("throttled")
+	if err := conditionalWriteError(other); err != other {
+		t.Errorf("unrelated error = %v, want it unchanged", err)
+	}
+	for _, item := range []map[string]types.AttributeValue{nil, {}} {
+		err := conditionalWriteError(&types.ConditionalCheckFailedException{Item: item})
+		if !errors.Is(err, NotFoundError) {
+			t.Errorf("item %v: err = %v, want not found", item, err)
+		}
+	}
+	err := conditionalWriteError(&types.ConditionalCheckFailedException{Item: map[string]types.AttributeValue{
+		"Id":                 &types.AttributeValueMemberS{Value: "b1"},
+		BookVersionAttribute: &types.AttributeValueMemberN{Value: "4"},
+	}})
+	var apiError *ApiError
+	if !errors.As(err, &apiError) || !errors.Is(err, PreconditionFailedError) || apiError.Details["etag"] != `"4"` {
+		t.Errorf("err = %v, want precondition failed with etag \"4\"", err)
+	}
+}
+
+// useDynamoDB points the global client at a stub that answers every call with the given status and body.
+func useDynamoDB(t *testing.T, statusCode int, body string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(statusCode)
+		_, _ = io.WriteString(w, body)
+	}))
+	previous := global.DynamoDBClient
+	global.DynamoDBClient = dynamodb.New(dynamodb.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	})
+	t.Cleanup(func() {
+		global.DynamoDBClient = previous
+		server.Close()
+	})
+}
+
+const conditionFailedBody = `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException",` +
+	`"message":"The conditional request failed"%s}`
+
+func TestBookConditionalRequests(t *testing.T) {
+	currentItem := `,"Item":{"Id":{"S":"b1"},"Version":{"N":"4"}}`
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		headers    map[string]string
+		body       string
+		dbStatus   int
+		dbBody     string
+		statusCode int
+		code       string
+		etag       string
+	}{
+		{
+			name:       "get not modified",
+			method:     http.MethodGet,
+			path:       "/books/b1",
+			headers:    map[string]string{"If-None-Match": `W/"4"`},
+			dbStatus:   http.StatusOK,
+			dbBody:     `{"Item":{"Id":{"S":"b1"},"Version":{"N":"4"}}}`,
+			statusCode: http.StatusNotModified,
+			etag:       `"4"`,
+		},
+		{
+			name:       "get missing",
+			method:     http.MethodGet,
+			path:       "/books/b1",
+			dbStatus:   http.StatusOK,
+			dbBody:     `{"Item":{}}`,
+			statusCode: http.StatusNotFound,
+			code:       "not_found",
+		},
+		{
+			name:       "post conflict",
+			method:     http.MethodPost,
+			path:       "/books",
+			body:       `{"title":"Dune"}`,
+			dbStatus:   http.StatusBadRequest,
+			dbBody:     strings.Replace(conditionFailedBody, "%s", "", 1),
+			statusCode: http.StatusConflict,
+			code:       "conflict",
+		},
+		{
+			name:       "put stale version",
+			method:     http.MethodPut,
+			path:       "/books/b1",
+			headers:    map[string]string{"If-Match": `"3"`},
+			body:       `{"title":"Dune"}`,
+			dbStatus:   http.StatusBadRequest,
+			dbBody:     strings.Replace(conditionFailedBody, "%s", currentItem, 1),
+			statusCode: http.StatusPreconditionFailed,
+			code:       "precondition_failed",
+		},
+		{
+			name:       "patch stale version",
+			method:     http.MethodPatch,
+			path:       "/books/b1",
+			headers:    map[string]string{"If-Match": `"3"`},
+			body:       `{"pages":412}`,
+			dbStatus:   http.StatusBadRequest,
+			dbBody:     strings.Replace(conditionFailedBody, "%s", currentItem, 1),
+			statusCode: http.StatusPreconditionFailed,
+			code:       "precondition_failed",
+		},
+		{
+			name:       "patch missing book",
+			method:     http.MethodPatch,
+			path:       "/books/b1",
+			body:       `{"pages":412}`,
+			dbStatus:   http.StatusBadRequest,
+			dbBody:     strings.Replace(conditionFailedBody, "%s", `,"Item":{}`, 1),
+			statusCode: http.StatusNotFound,
+			code:       "not_found",
+		},
+		{
+			name:       "delete stale version",
+			method:     http.MethodDelete,
+			path:       "/books/b1",
+			headers:    map[string]string{"If-Match": `"3"`},
+			dbStatus:   http.StatusBadRequest,
+			dbBody:     strings.Replace(conditionFailedBody, "%s", currentItem, 1),
+			statusCode: http.StatusPreconditionFailed,
+			code:       "precondition_failed",
+		},
+		{
+			name:       "delete unparseable if-match",
+			method:     http.MethodDelete,
+			path:       "/books/b1",
+			headers:    map[string]string{"If-Match": `W/"3"`},
+			statusCode: http.StatusPreconditionFailed,
+			code:       "precondition_failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useDynamoDB(t, test.dbStatus, test.dbBody)
+			request := apiGwRequest(test.method, test.path)
+			request.Headers = map[string]string{"Content-Type": JsonContentType}
+			for name, value := range test.headers {
+				request.Headers[name] = value
+			}
+			request.Body = test.body
+
+			response, err := HandleRequest(context.Background(), request)
+			if err != nil {
+				t.Fatalf("HandleRequest: %v", err)
+			}
+			if response.StatusCode != test.statusCode {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, test.statusCode, response.Body)
+			}
+			if etag := response.Headers[http.CanonicalHeaderKey(ETagHeader)]; etag != test.etag {
+				t.Errorf("ETag = %q, want %q", etag, test.etag)
+			}
+			if test.code == "" {
+				return
+			}
+			var envelope ErrorEnvelope
+			if err := json.Unmarshal([]byte(response.Body), &envelope); err != nil {
+				t.Fatalf("body %q: %v", response.Body, err)
+			}
+			if envelope.Code != test.code {
+				t.Errorf("code = %q, want %q", envelope.Code, test.code)
+			}
+			if test.code == "precondition_failed" && test.dbBody != "" && envelope.Details["etag"] != `"4"` {
+				t.Errorf("details = %v, want the current etag", envelope.Details)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	ETagHeader     = "ETag"
+	WeakETagPrefix = "W/"
+	ETagWildcard   = "*"
+)
+
+func FormatETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+func ParseETags(header string) ([]string, bool) {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == ETagWildcard {
+			return nil, true
+		}
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, false
+}
+
+func ETagMatches(header, etag string, weak bool) bool {
+	tags, wildcard := ParseETags(header)
+	if wildcard {
+		return true
+	}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, WeakETagPrefix) || strings.HasPrefix(etag, WeakETagPrefix) {
+			if weak && strings.TrimPrefix(tag, WeakETagPrefix) == strings.TrimPrefix(etag, WeakETagPrefix) {
+				return true
+			}
+			continue
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func ParseVersionETags(header string) ([]int64, bool) {
+	tags, wildcard := ParseETags(header)
+	if wildcard {
+		return nil, true
+	}
+	var versions []int64
+	for _, tag := range tags {
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			continue
+		}
+		version, err := strconv.ParseInt(unquoted, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, false
+}
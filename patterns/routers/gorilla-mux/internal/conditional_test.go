@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseVersionETags(t *testing.T) {
+	tests := []struct {
+		header   string
+		versions []int64
+		wildcard bool
+	}{
+		{`"3"`, []int64{3}, false},
+		{`"1", "2"`, []int64{1, 2}, false},
+		{`*`, nil, true},
+		{`"1", *`, nil, true},
+		{`W/"4"`, nil, false},
+		{`"abc", "5"`, []int64{5}, false},
+		{`3`, nil, false},
+		{``, nil, false},
+	}
+	for _, test := range tests {
+		versions, wildcard := ParseVersionETags(test.header)
+		if !slices.Equal(versions, test.versions) || wildcard != test.wildcard {
+			t.Errorf("ParseVersionETags(%q) = %v, %v, want %v, %v",
+				test.header, versions, wildcard, test.versions, test.wildcard)
+		}
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		weak   bool
+		match  bool
+	}{
+		{`"2"`, `"2"`, false, true},
+		{`"1", "2"`, `"2"`, false, true},
+		{`"1"`, `"2"`, false, false},
+		{`*`, `"2"`, false, true},
+		{`W/"2"`, `"2"`, false, false},
+		{`W/"2"`, `"2"`, true, true},
+		{`"2"`, `W/"2"`, true, true},
+		{`W/"1"`, `"2"`, true, false},
+		{``, `"2"`, true, false},
+	}
+	for _, test := range tests {
+		if got := ETagMatches(test.header, test.etag, test.weak); got != test.match {
+			t.Errorf("ETagMatches(%q, %q, %v) = %v, want %v", test.header, test.etag, test.weak, got, test.match)
+		}
+	}
+}
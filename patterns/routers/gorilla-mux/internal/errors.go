@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"dunno/internal/global"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var NotFoundError = errors.New("resource not found")
+var ConflictError = errors.New("resource conflict")
+var PreconditionFailedError = errors.New("precondition failed")
+var ValidationError = errors.New("validation failed")
+var UnauthorizedError = errors.New("authentication required")
+var ForbiddenError = errors.New("access denied")
+var PayloadTooLargeError = errors.New("payload too large")
+var MethodNotAllowedError = errors.New("method not allowed")
+
+const InternalErrorCode = "internal_error"
+
+type errorKind struct {
+	err        error
+	statusCode int
+	code       string
+}
+
+var errorKinds = []errorKind{
+	{NotFoundError, http.StatusNotFound, "not_found"},
+	{ConflictError, http.StatusConflict, "conflict"},
+	{PreconditionFailedError, http.StatusPreconditionFailed, "precondition_failed"},
+	{ValidationError, http.StatusBadRequest, "validation_failed"},
+	{UnauthorizedError, http.StatusUnauthorized, "unauthorized"},
+	{ForbiddenError, http.StatusForbidden, "forbidden"},
+	{PayloadTooLargeError, http.StatusRequestEntityTooLarge, "payload_too_large"},
+	{MethodNotAllowedError, http.StatusMethodNotAllowed, "method_not_allowed"},
+}
+
+type ApiError struct {
+	Kind    error
+	Message string
+	Details map[string]any
+	Cause   error
+}
+
+func NewApiError(kind error, message string) *ApiError {
+	return &ApiError{Kind: kind, Message: message}
+}
+
+func (e *ApiError) WithDetails(details map[string]any) *ApiError {
+	e.Details = details
+	return e
+}
+
+func (e *ApiError) WithCause(cause error) *ApiError {
+	e.Cause = cause
+	return e
+}
+
+func (e *ApiError) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *ApiError) Unwrap() []error {
+	var errs []error
+	for _, err := range []error{e.Kind, e.Cause} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+type ErrorEnvelope struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	RequestId string         `json:"requestId,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+func resolveError(err error) (int, ErrorEnvelope) {
+	var bindingError *BindingError
+	if errors.As(err, &bindingError) {
+		err = NewApiError(ValidationError, bindingError.Error()).WithDetails(map[string]any{
+			"source": bindingError.Source,
+			"name":   bindingError.Name,
+			"reason": bindingError.Reason,
+		})
+	}
+	for _, kind := range errorKinds {
+		if !errors.Is(err, kind.err) {
+			continue
+		}
+		envelope := ErrorEnvelope{Code: kind.code, Message: kind.err.Error()}
+		var apiError *ApiError
+		if errors.As(err, &apiError) {
+			if apiError.Message != "" {
+				envelope.Message = apiError.Message
+			}
+			envelope.Details = apiError.Details
+		}
+		return kind.statusCode, envelope
+	}
+	return http.StatusInternalServerError, ErrorEnvelope{
+		Code:    InternalErrorCode,
+		Message: http.StatusText(http.StatusInternalServerError),
+	}
+}
+
+func writeErrorEnvelope(w http.ResponseWriter, r *http.Request, statusCode int, envelope ErrorEnvelope) {
+	envelope.RequestId = RequestIdFromContext(r.Context())
+	encodeErrorEnvelope(w, statusCode, envelope)
+}
+
+func encodeErrorEnvelope(w http.ResponseWriter, statusCode int, envelope ErrorEnvelope) {
+	body, _ := json.Marshal(envelope)
+	w.Header().Set("Content-Type", JsonContentType)
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode, envelope := resolveError(err)
+	if statusCode >= http.StatusInternalServerError {
+		global.Logger.Errorw("Request failed",
+			"requestId", RequestIdFromContext(r.Context()),
+			"status", statusCode,
+			"error", err)
+	} else {
+		global.Logger.Infow("Request rejected",
+			"requestId", RequestIdFromContext(r.Context()),
+			"status", statusCode,
+			"code", envelope.Code,
+			"error", err)
+	}
+	writeErrorEnvelope(w, r, statusCode, envelope)
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+		details map[string]any
+	}{
+		{"not found", NotFoundError, http.StatusNotFound, "not_found", "resource not found", nil},
+		{"conflict", ConflictError, http.StatusConflict, "conflict", "resource conflict", nil},
+		{"precondition failed", PreconditionFailedError, http.StatusPreconditionFailed, "precondition_failed", "precondition failed", nil},
+		{"validation", ValidationError, http.StatusBadRequest, "validation_failed", "validation failed", nil},
+		{"unauthorized", UnauthorizedError, http.StatusUnauthorized, "unauthorized", "authentication required", nil},
+		{"forbidden", ForbiddenError, http.StatusForbidden, "forbidden", "access denied", nil},
+		{"payload too large", PayloadTooLargeError, http.StatusRequestEntityTooLarge, "payload_too_large", "payload too large", nil},
+		{"method not allowed", MethodNotAllowedError, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", nil},
+		{"wrapped sentinel", fmt.Errorf("loading book: %w", NotFoundError), http.StatusNotFound, "not_found", "resource not found", nil},
+		{
+			"api error",
+			NewApiError(ConflictError, "book already exists").WithDetails(map[string]any{"id": "b1"}),
+			http.StatusConflict, "conflict", "book already exists", map[string]any{"id": "b1"},
+		},
+		{
+			"wrapped api error",
+			fmt.Errorf("saving: %w", NewApiError(PreconditionFailedError, "stale version")),
+			http.StatusPreconditionFailed, "precondition_failed", "stale version", nil,
+		},
+		{"api error without message", NewApiError(ForbiddenError, ""), http.StatusForbidden, "forbidden", "access denied", nil},
+		{
+			"binding error",
+			&BindingError{Source: "query", Name: "limit", Reason: "must be a number"},
+			http.StatusBadRequest, "validation_failed", "",
+			map[string]any{"source": "query", "name": "limit", "reason": "must be a number"},
+		},
+		{"unknown error", errors.New("dial tcp 10.0.0.1: secret detail"), http.StatusInternalServerError, InternalErrorCode, "Internal Server Error", nil},
+		{
+			"api error with unknown kind",
+			NewApiError(errors.New("boom"), "leaked message"),
+			http.StatusInternalServerError, InternalErrorCode, "Internal Server Error", nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, envelope := resolveError(test.err)
+			if status != test.status || envelope.Code != test.code {
+				t.Errorf("resolveError = %d %s, want %d %s", status, envelope.Code, test.status, test.code)
+			}
+			if test.message != "" && envelope.Message != test.message {
+				t.Errorf("message = %q, want %q", envelope.Message, test.message)
+			}
+			if !reflect.DeepEqual(envelope.Details, test.details) {
+				t.Errorf("details = %v, want %v", envelope.Details, test.details)
+			}
+			if status == http.StatusInternalServerError && strings.Contains(envelope.Message, test.err.Error()) {
+				t.Errorf("message %q leaks the error", envelope.Message)
+			}
+		})
+	}
+}
+
+func TestApiErrorUnwrapsKindAndCause(t *testing.T) {
+	cause := errors.New("condition check failed")
+	err := NewApiError(ConflictError, "book already exists").WithCause(cause)
+	if got := err.Error(); got != "book already exists: condition check failed" {
+		t.Errorf("Error() = %q", got)
+	}
+	if !errors.Is(err, ConflictError) || !errors.Is(err, cause) {
+		t.Error("errors.Is does not see the kind and the cause")
+	}
+	if errors.Is(NewApiError(ConflictError, "book already exists"), cause) {
+		t.Error("errors.Is matched a cause that was never set")
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 type Config struct {
 	BooksTableArn string `env:"BOOKS_TABLE_ARN"`
+	MaxBodyBytes  int64  `env:"MAX_BODY_BYTES" envDefault:"1048576"`
 }
 
 var Logger *zap.SugaredLogger
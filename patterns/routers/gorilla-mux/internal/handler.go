@@ -2,48 +2,65 @@ package internal
 
 import (
 	"context"
+	"dunno/internal/adapter"
 	"dunno/internal/global"
 	"net/http"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/gorilla/mux"
-	"go.uber.org/zap"
 )
 
+var allowedMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, NewApiError(NotFoundError, "no route matches "+r.URL.Path))
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+	for _, method := range allowedMethods {
+		candidate := r.Clone(r.Context())
+		candidate.Method = method
+		var routeMatch mux.RouteMatch
+		if global.Router.Match(candidate, &routeMatch) && routeMatch.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+	}
+	writeError(w, r, NewApiError(MethodNotAllowedError, r.Method+" is not allowed on "+r.URL.Path))
+}
+
 func HandleRequest(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-	method := request.RequestContext.HTTP.Method
-	path := request.RawPath
-	global.Logger.Info(ctx, "Request received",
-		zap.String("path", request.RawPath),
-		zap.String("method", method),
-		zap.String("body", request.Body))
-	httpRequest, err := http.NewRequestWithContext(ctx, method, path, strings.NewReader(request.Body))
+	responseWriter := NewApiGwResponseWriter()
+	httpRequest, err := adapter.ToHttpRequest(ctx, request)
 	if err != nil {
-		return events.APIGatewayV2HTTPResponse{}, err
-	}
-	headers := make(map[string][]string)
-	for key, value := range request.Headers {
-		parts := strings.Split(value, ",")
-		for i := range parts {
-			parts[i] = strings.TrimSpace(parts[i])
+		global.Logger.Infow("Invalid request", "path", request.RawPath, "error", err)
+		statusCode, envelope := resolveError(NewApiError(ValidationError, "request could not be decoded"))
+		envelope.RequestId = request.RequestContext.RequestID
+		if envelope.RequestId != "" {
+			responseWriter.Header().Set(RequestIdHeader, envelope.RequestId)
 		}
-		headers[key] = parts
+		encodeErrorEnvelope(responseWriter, statusCode, envelope)
+		return responseWriter.GetResponse(), nil
 	}
-	httpRequest.Header = headers
-	global.Logger.Info("Headers parsed", zap.Any("headers", headers))
 
 	var routeMatch mux.RouteMatch
-	if global.Router.Match(httpRequest, &routeMatch) {
-		global.Logger.Info(ctx, "Route matched", zap.String("path", path))
-		responseWriter := NewApiGwResponseWriter()
-		httpRequest = WithPathParams(httpRequest, routeMatch.Vars)
-		httpRequest = WithQueryParams(httpRequest, request.QueryStringParameters)
-		routeMatch.Handler.ServeHTTP(responseWriter, httpRequest)
-		return responseWriter.GetResponse(), nil
+	if !global.Router.Match(httpRequest, &routeMatch) {
+		routeMatch.Handler = global.Router.NotFoundHandler
 	}
-	global.Logger.Info(ctx, "Route not matched", zap.String("path", path))
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: http.StatusNotFound,
-	}, nil
+	httpRequest = WithPathParams(httpRequest, routeMatch.Vars)
+	httpRequest = WithQueryParams(httpRequest, request.QueryStringParameters)
+	routeMatch.Handler.ServeHTTP(responseWriter, httpRequest)
+	return responseWriter.GetResponse(), nil
 }
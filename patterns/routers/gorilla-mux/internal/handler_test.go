@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandleRequestErrorEnvelopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		request events.APIGatewayV2HTTPRequest
+		status  int
+		code    string
+		allow   string
+	}{
+		{
+			name:    "unmatched route",
+			request: apiGwRequest(http.MethodGet, "/authors"),
+			status:  http.StatusNotFound,
+			code:    "not_found",
+		},
+		{
+			name:    "method not allowed",
+			request: apiGwRequest(http.MethodPost, "/books/b1"),
+			status:  http.StatusMethodNotAllowed,
+			code:    "method_not_allowed",
+			allow:   "GET, PUT, PATCH, DELETE",
+		},
+		{
+			name: "undecodable event",
+			request: func() events.APIGatewayV2HTTPRequest {
+				request := apiGwRequest(http.MethodPost, "/books")
+				request.Body = "not base64!"
+				request.IsBase64Encoded = true
+				return request
+			}(),
+			status: http.StatusBadRequest,
+			code:   "validation_failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := HandleRequest(context.Background(), test.request)
+			if err != nil {
+				t.Fatalf("HandleRequest: %v", err)
+			}
+			if response.StatusCode != test.status {
+				t.Fatalf("status = %d, want %d", response.StatusCode, test.status)
+			}
+			if got := response.Headers["Content-Type"]; got != JsonContentType {
+				t.Errorf("Content-Type = %q, want %q", got, JsonContentType)
+			}
+			if got := response.Headers[RequestIdHeader]; got != "request-1" {
+				t.Errorf("%s = %q, want request-1", RequestIdHeader, got)
+			}
+			if got := response.Headers["Allow"]; got != test.allow {
+				t.Errorf("Allow = %q, want %q", got, test.allow)
+			}
+			var envelope ErrorEnvelope
+			if err := json.Unmarshal([]byte(response.Body), &envelope); err != nil {
+				t.Fatalf("body %q: %v", response.Body, err)
+			}
+			if envelope.Code != test.code || envelope.RequestId != "request-1" || envelope.Message == "" {
+				t.Errorf("envelope = %+v, want code %s for request-1", envelope, test.code)
+			}
+		})
+	}
+}
+
+func apiGwRequest(method, path string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		RawPath: path,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RequestID: "request-1",
+			HTTP:      events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: method, Path: path},
+		},
+	}
+}
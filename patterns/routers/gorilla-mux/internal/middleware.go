@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"context"
+	"dunno/internal/adapter"
+	"dunno/internal/global"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	RequestIdHeader    = "X-Request-Id"
+	MaxRequestIdLength = 128
+)
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:+=/-]+$`)
+
+type Middleware = mux.MiddlewareFunc
+
+type requestIdKey struct{}
+type principalKey struct{}
+
+type Principal struct {
+	Subject string
+	Claims  map[string]string
+	Scopes  []string
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	written    int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(body []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(body)
+	r.written += n
+	return n, err
+}
+
+func RequestIdMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestId string
+		if requestContext, ok := adapter.FromContext(r.Context()); ok {
+			requestId = requestContext.RequestID
+		}
+		if requestId == "" {
+			requestId = r.Header.Get(RequestIdHeader)
+			if len(requestId) > MaxRequestIdLength || !requestIdPattern.MatchString(requestId) {
+				requestId = uuid.New().String()
+			}
+		}
+		w.Header().Set(RequestIdHeader, requestId)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIdKey{}, requestId)))
+	})
+}
+
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		global.Logger.Infow("Request received",
+			"requestId", RequestIdFromContext(r.Context()),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"contentLength", r.ContentLength)
+		next.ServeHTTP(recorder, r)
+		global.Logger.Infow("Request completed",
+			"requestId", RequestIdFromContext(r.Context()),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.statusCode,
+			"responseBytes", recorder.written,
+			"duration", time.Since(start))
+	})
+}
+
+type timingWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	wroteHeader bool
+}
+
+func (w *timingWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		elapsed := time.Since(w.start)
+		w.Header().Set("Server-Timing", fmt.Sprintf("app;dur=%.3f", float64(elapsed.Microseconds())/1000))
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *timingWriter) Write(body []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(body)
+}
+
+func TimingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&timingWriter{ResponseWriter: w, start: time.Now()}, r)
+	})
+}
+
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				global.Logger.Errorw("Handler panicked",
+					"requestId", RequestIdFromContext(r.Context()),
+					"panic", fmt.Sprint(recovered),
+					"stack", string(debug.Stack()))
+				if recorder.statusCode != 0 {
+					return
+				}
+				writeErrorEnvelope(w, r, http.StatusInternalServerError, ErrorEnvelope{
+					Code:    InternalErrorCode,
+					Message: http.StatusText(http.StatusInternalServerError),
+				})
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+func AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestContext, ok := adapter.FromContext(r.Context())
+		if !ok || requestContext.Authorizer == nil || requestContext.Authorizer.JWT == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		jwt := requestContext.Authorizer.JWT
+		principal := &Principal{
+			Subject: jwt.Claims["sub"],
+			Claims:  jwt.Claims,
+			Scopes:  jwt.Scopes,
+		}
+		if len(principal.Scopes) == 0 && jwt.Claims["scope"] != "" {
+			principal.Scopes = strings.Fields(jwt.Claims["scope"])
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+func RequireAuth(scopes ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok || principal.Subject == "" {
+				writeError(w, r, UnauthorizedError)
+				return
+			}
+			for _, scope := range scopes {
+				if !slices.Contains(principal.Scopes, scope) {
+					writeError(w, r, NewApiError(ForbiddenError, "missing scope "+scope).WithDetails(map[string]any{
+						"scope": scope,
+					}))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func BodyLimitMiddleware(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				writeError(w, r, NewApiError(PayloadTooLargeError, fmt.Sprintf("request body exceeds %d bytes", maxBytes)))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func globalMiddlewares() []Middleware {
+	return []Middleware{
+		RequestIdMiddleware,
+		LoggingMiddleware,
+		TimingMiddleware,
+		RecoveryMiddleware,
+		AuthMiddleware,
+		BodyLimitMiddleware(global.AppConfig.MaxBodyBytes),
+	}
+}
+
+func init() {
+	middlewares := globalMiddlewares()
+	global.Router.Use(middlewares...)
+	// mux only runs Use middleware for matched routes, so the fallback handlers get the same chain explicitly.
+	global.Router.NotFoundHandler = Chain(http.HandlerFunc(notFoundHandler), middlewares...)
+	global.Router.MethodNotAllowedHandler = Chain(http.HandlerFunc(methodNotAllowedHandler), middlewares...)
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"dunno/internal/adapter"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRequestIdMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		gatewayId string
+		header    string
+		want      string
+	}{
+		{"gateway id wins over header", "gateway-id", "client-id", "gateway-id"},
+		{"header without gateway id", "", "client-id", "client-id"},
+		{"header too long", "", strings.Repeat("a", MaxRequestIdLength+1), ""},
+		{"header with invalid characters", "", "id\nforged log line", ""},
+		{"no id", "", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.gatewayId != "" {
+				request = request.WithContext(adapter.NewContext(request.Context(),
+					events.APIGatewayV2HTTPRequestContext{RequestID: test.gatewayId}))
+			}
+			if test.header != "" {
+				request.Header.Set(RequestIdHeader, test.header)
+			}
+			var seen string
+			recorder := httptest.NewRecorder()
+			RequestIdMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIdFromContext(r.Context())
+			})).ServeHTTP(recorder, request)
+
+			if test.want != "" && seen != test.want {
+				t.Errorf("request id = %q, want %q", seen, test.want)
+			}
+			if test.want == "" && (seen == "" || seen == test.header) {
+				t.Errorf("request id = %q, want a generated id", seen)
+			}
+			if got := recorder.Header().Get(RequestIdHeader); got != seen {
+				t.Errorf("%s header = %q, want %q", RequestIdHeader, got, seen)
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddlewareWritesEnvelope(t *testing.T) {
+	w := NewApiGwResponseWriter()
+	RecoveryMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	response := w.GetResponse()
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusInternalServerError)
+	}
+	var envelope ErrorEnvelope
+	if err := json.Unmarshal([]byte(response.Body), &envelope); err != nil || envelope.Code != InternalErrorCode {
+		t.Errorf("body = %q, want internal error envelope", response.Body)
+	}
+	if strings.Contains(response.Body, "boom") {
+		t.Errorf("panic value leaked: %q", response.Body)
+	}
+}
+
+func TestRecoveryMiddlewareKeepsWrittenResponse(t *testing.T) {
+	w := NewApiGwResponseWriter()
+	RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	response := w.GetResponse()
+	if response.StatusCode != http.StatusAccepted || response.Body != "partial" {
+		t.Errorf("response = %d %q, want %d %q", response.StatusCode, response.Body, http.StatusAccepted, "partial")
+	}
+}
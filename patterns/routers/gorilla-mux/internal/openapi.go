@@ -0,0 +1,427 @@
+package internal
+
+import (
+	"dunno/internal/global"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	OpenApiVersion  = "3.0.3"
+	OpenApiPath     = "/openapi.json"
+	ApiTitle        = "Books API"
+	ApiVersion      = "1.0.0"
+	JsonContentType = "application/json"
+)
+
+type RouteInfo struct {
+	Method       string
+	Path         string
+	RequestType  reflect.Type
+	ResponseType reflect.Type
+	ParamsType   reflect.Type
+	ContentTypes []string
+	Errors       []error
+}
+
+var (
+	routesMutex sync.Mutex
+	routes      []RouteInfo
+)
+
+func recordRoute(info RouteInfo) {
+	routesMutex.Lock()
+	defer routesMutex.Unlock()
+	for _, route := range routes {
+		if route.Method == info.Method && route.Path == info.Path {
+			panic(fmt.Sprintf("route %s %s registered twice", info.Method, info.Path))
+		}
+	}
+	routes = append(routes, info)
+}
+
+// DocumentErrors adds error kinds a route returns to its OpenAPI responses, for errors its types do not imply.
+func DocumentErrors(route *mux.Route, kinds ...error) *mux.Route {
+	path, _ := route.GetPathTemplate()
+	methods, _ := route.GetMethods()
+	routesMutex.Lock()
+	defer routesMutex.Unlock()
+	for i := range routes {
+		if routes[i].Path == path && slices.Contains(methods, routes[i].Method) {
+			routes[i].Errors = append(routes[i].Errors, kinds...)
+		}
+	}
+	return route
+}
+
+func Routes() []RouteInfo {
+	routesMutex.Lock()
+	defer routesMutex.Unlock()
+	return slices.Clone(routes)
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type ApiResponse struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// HeaderDescriber is implemented by response types that are served with additional headers.
+type HeaderDescriber interface {
+	DescribeHeaders() map[string]string
+}
+
+type Operation struct {
+	OperationId string                 `json:"operationId"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]ApiResponse `json:"responses"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type OpenApiInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenApiDocument struct {
+	OpenApi    string                          `json:"openapi"`
+	Info       OpenApiInfo                     `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type schemaGenerator struct {
+	schemas map[string]*Schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name, false, true
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false, false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty"), true
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := g.schema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		return g.structSchema(t)
+	}
+	return &Schema{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+	if t.Name() == "" {
+		ref = nil
+	} else if _, ok := g.schemas[t.Name()]; ok {
+		return ref
+	}
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	if ref != nil {
+		g.schemas[t.Name()] = schema
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		schema.Properties[name] = g.schema(field.Type)
+		if isRequired(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	if ref == nil {
+		return schema
+	}
+	return ref
+}
+
+func parseBound(field reflect.StructField, tag string) *float64 {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return nil
+	}
+	bound, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &bound
+}
+
+func typedDefault(schemaType, value string) any {
+	var typed any
+	var err error
+	switch schemaType {
+	case "integer":
+		typed, err = strconv.ParseInt(value, 10, 64)
+	case "number":
+		typed, err = strconv.ParseFloat(value, 64)
+	case "boolean":
+		typed, err = strconv.ParseBool(value)
+	default:
+		return value
+	}
+	if err != nil {
+		return value
+	}
+	return typed
+}
+
+func (g *schemaGenerator) parameters(t reflect.Type) []Parameter {
+	if t == nil {
+		return nil
+	}
+	var parameters []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		source, name, ok := paramSource(field)
+		if !ok || !field.IsExported() {
+			continue
+		}
+		schema := g.schema(field.Type)
+		if defaultValue, ok := field.Tag.Lookup(DefaultTag); ok {
+			schema.Default = typedDefault(schema.Type, defaultValue)
+		}
+		schema.Minimum = parseBound(field, MinTag)
+		schema.Maximum = parseBound(field, MaxTag)
+		parameters = append(parameters, Parameter{
+			Name:     name,
+			In:       source,
+			Required: isRequired(field) || source == PathTag,
+			Schema:   schema,
+		})
+	}
+	return parameters
+}
+
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?}`)
+
+func operationId(method, path string) string {
+	var builder strings.Builder
+	builder.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(pathVariable.ReplaceAllString(path, "by-$1"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.'
+	}) {
+		builder.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return builder.String()
+}
+
+func responseHeaders(t reflect.Type) map[string]Header {
+	describer, ok := reflect.Zero(t).Interface().(HeaderDescriber)
+	if !ok {
+		return nil
+	}
+	headers := make(map[string]Header)
+	for name, description := range describer.DescribeHeaders() {
+		headers[name] = Header{Description: description, Schema: &Schema{Type: "string"}}
+	}
+	return headers
+}
+
+func hasHeaderParam(t reflect.Type, header string) bool {
+	if t == nil {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := t.Field(i).Tag.Lookup(HeaderTag); ok && strings.EqualFold(name, header) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *schemaGenerator) errorResponse(operation *Operation, err error) {
+	for _, kind := range errorKinds {
+		if kind.err == err {
+			operation.Responses[strconv.Itoa(kind.statusCode)] = ApiResponse{
+				Description: http.StatusText(kind.statusCode),
+				Content:     map[string]MediaType{JsonContentType: {Schema: g.schema(typeOf[ErrorEnvelope]())}},
+			}
+			return
+		}
+	}
+}
+
+func (g *schemaGenerator) operation(route RouteInfo) Operation {
+	errorContent := map[string]MediaType{JsonContentType: {Schema: g.schema(typeOf[ErrorEnvelope]())}}
+	operation := Operation{
+		OperationId: operationId(route.Method, route.Path),
+		Parameters:  g.parameters(route.ParamsType),
+		Responses: map[string]ApiResponse{
+			"400": {Description: "Invalid request", Content: errorContent},
+			"500": {Description: "Internal server error", Content: errorContent},
+		},
+	}
+	if pathVariable.MatchString(route.Path) {
+		operation.Responses["404"] = ApiResponse{Description: "Not found", Content: errorContent}
+	}
+	if hasHeaderParam(route.ParamsType, "If-Match") {
+		g.errorResponse(&operation, PreconditionFailedError)
+	}
+	for _, err := range route.Errors {
+		g.errorResponse(&operation, err)
+	}
+	if route.RequestType != typeOf[Unit]() {
+		content := make(map[string]MediaType)
+		contentTypes := route.ContentTypes
+		if len(contentTypes) == 0 {
+			contentTypes = []string{JsonContentType}
+		}
+		for _, contentType := range contentTypes {
+			content[contentType] = MediaType{Schema: g.schema(route.RequestType)}
+		}
+		operation.RequestBody = &RequestBody{Required: true, Content: content}
+	}
+	headers := responseHeaders(route.ResponseType)
+	if hasHeaderParam(route.ParamsType, "If-None-Match") {
+		operation.Responses["304"] = ApiResponse{Description: "Not modified", Headers: headers}
+	}
+	if route.ResponseType == typeOf[Unit]() {
+		operation.Responses["204"] = ApiResponse{Description: "No content", Headers: headers}
+	} else {
+		operation.Responses["200"] = ApiResponse{
+			Description: "Successful response",
+			Headers:     headers,
+			Content:     map[string]MediaType{JsonContentType: {Schema: g.schema(route.ResponseType)}},
+		}
+	}
+	return operation
+}
+
+func GenerateOpenApi() *OpenApiDocument {
+	generator := &schemaGenerator{schemas: make(map[string]*Schema)}
+	document := &OpenApiDocument{
+		OpenApi: OpenApiVersion,
+		Info: OpenApiInfo{
+			Title:   ApiTitle,
+			Version: ApiVersion,
+		},
+		Paths: make(map[string]map[string]Operation),
+	}
+	for _, route := range Routes() {
+		path := pathVariable.ReplaceAllString(route.Path, "{$1}")
+		if document.Paths[path] == nil {
+			document.Paths[path] = make(map[string]Operation)
+		}
+		document.Paths[path][strings.ToLower(route.Method)] = generator.operation(route)
+	}
+	document.Components.Schemas = generator.schemas
+	return document
+}
+
+func ServeOpenApi(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(GenerateOpenApi())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", JsonContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func init() {
+	global.Router.HandleFunc(OpenApiPath, ServeOpenApi).Methods(http.MethodGet)
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func findOperation(t *testing.T, document *OpenApiDocument, path, method string) Operation {
+	t.Helper()
+	operation, ok := document.Paths[path][method]
+	if !ok {
+		t.Fatalf("no %s %s operation", method, path)
+	}
+	return operation
+}
+
+func TestOpenApiConditionalResponses(t *testing.T) {
+	document := GenerateOpenApi()
+	tests := []struct {
+		method   string
+		path     string
+		statuses []string
+		header   string
+	}{
+		{"get", "/books/{id}", []string{"200", "304", "404"}, "If-None-Match"},
+		{"put", "/books/{id}", []string{"200", "404", "412"}, "If-Match"},
+		{"patch", "/books/{id}", []string{"200", "404", "412"}, "If-Match"},
+		{"delete", "/books/{id}", []string{"204", "404", "412"}, "If-Match"},
+		{"post", "/books", []string{"200", "409"}, ""},
+	}
+	for _, test := range tests {
+		operation := findOperation(t, document, test.path, test.method)
+		for _, status := range test.statuses {
+			if _, ok := operation.Responses[status]; !ok {
+				t.Errorf("%s %s: missing %s response", test.method, test.path, status)
+			}
+		}
+		if response, ok := operation.Responses["200"]; ok {
+			if _, ok := response.Headers[ETagHeader]; !ok {
+				t.Errorf("%s %s: 200 response does not document %s", test.method, test.path, ETagHeader)
+			}
+		}
+		if test.header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(operation.Parameters, func(parameter Parameter) bool {
+			return parameter.In == HeaderTag && parameter.Name == test.header
+		}) {
+			t.Errorf("%s %s: missing %s header parameter", test.method, test.path, test.header)
+		}
+	}
+	if _, ok := findOperation(t, document, "/books", "get").Responses["412"]; ok {
+		t.Error("GET /books documents 412 without an If-Match parameter")
+	}
+}
+
+func TestOpenApiRequiredFollowsTag(t *testing.T) {
+	type taggedRequest struct {
+		Name  string `json:"name" required:"true"`
+		Notes string `json:"notes"`
+	}
+	generator := &schemaGenerator{schemas: make(map[string]*Schema)}
+	generator.schema(typeOf[taggedRequest]())
+	if required := generator.schemas["taggedRequest"].Required; !slices.Equal(required, []string{"name"}) {
+		t.Errorf("required = %v, want [name]", required)
+	}
+	generator.schema(typeOf[BookRequest]())
+	if required := generator.schemas["BookRequest"].Required; len(required) != 0 {
+		t.Errorf("BookRequest required = %v, but SaveBook does not enforce any field", required)
+	}
+}
+
+func TestRecordRouteRejectsDuplicates(t *testing.T) {
+	routesMutex.Lock()
+	previous := slices.Clone(routes)
+	routesMutex.Unlock()
+	t.Cleanup(func() {
+		routesMutex.Lock()
+		routes = previous
+		routesMutex.Unlock()
+	})
+	router := mux.NewRouter()
+	handler := func(*Request[Unit]) *Response[Unit] { return &Response[Unit]{StatusCode: http.StatusNoContent} }
+	RegisterFunc(router, "/duplicate", http.MethodPost, handler)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a duplicate route")
+		}
+	}()
+	RegisterFuncMatchContentType(router, "/duplicate", http.MethodPost, handler, JsonContentType)
+}
@@ -1,22 +1,27 @@
 package internal
 
 import (
+	"bytes"
+	"encoding/base64"
+	"mime"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
 type ApiGwResponseWriter struct {
-	header     http.Header
-	statusCode int
-	body       []byte
+	header      http.Header
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
 }
 
 func NewApiGwResponseWriter() *ApiGwResponseWriter {
 	return &ApiGwResponseWriter{
 		header:     make(http.Header),
-		statusCode: 200,
-		body:       nil,
+		statusCode: http.StatusOK,
 	}
 }
 
@@ -25,18 +30,64 @@ func (w *ApiGwResponseWriter) Header() http.Header {
 }
 
 func (w *ApiGwResponseWriter) Write(body []byte) (int, error) {
-	w.body = body
-	return len(body), nil
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(body)
 }
 
 func (w *ApiGwResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
 	w.statusCode = statusCode
+	w.wroteHeader = true
+}
+
+func IsTextContentType(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "us-ascii") {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		mediaType == "application/xml" ||
+		mediaType == "application/javascript" ||
+		mediaType == "application/x-www-form-urlencoded" ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml")
 }
 
 func (w *ApiGwResponseWriter) GetResponse() events.APIGatewayV2HTTPResponse {
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode:        w.statusCode,
-		MultiValueHeaders: w.header,
-		Body:              string(w.body),
+	body := w.body.Bytes()
+	if len(body) > 0 && w.header.Get("Content-Type") == "" {
+		w.header.Set("Content-Type", http.DetectContentType(body))
+	}
+	response := events.APIGatewayV2HTTPResponse{
+		StatusCode: w.statusCode,
+		Headers:    make(map[string]string, len(w.header)),
+	}
+	for key, values := range w.header {
+		if len(values) == 0 {
+			continue
+		}
+		if http.CanonicalHeaderKey(key) == "Set-Cookie" {
+			response.Cookies = append(response.Cookies, values...)
+			continue
+		}
+		response.Headers[key] = strings.Join(values, ", ")
+	}
+	if len(body) == 0 {
+		return response
+	}
+	if IsTextContentType(w.header.Get("Content-Type")) && utf8.Valid(body) {
+		response.Body = string(body)
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(body)
+		response.IsBase64Encoded = true
 	}
+	return response
 }
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func TestResponseWriterAccumulatesChunks(t *testing.T) {
+	w := NewApiGwResponseWriter()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"title":`))
+	_, _ = w.Write([]byte(`"Dune"}`))
+
+	response := w.GetResponse()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+	if response.Body != `{"title":"Dune"}` || response.IsBase64Encoded {
+		t.Errorf("body = %q (base64 %v), want plain JSON", response.Body, response.IsBase64Encoded)
+	}
+}
+
+func TestResponseWriterEncodesBinaryBodies(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"binary content type", "image/png", []byte{0x89, 'P', 'N', 'G'}},
+		{"detected binary", "", []byte{0x00, 0x01, 0xff, 0xfe}},
+		{"text with invalid UTF-8", "text/plain", []byte{'a', 0xff, 'b'}},
+		{"text with non UTF-8 charset", "text/plain; charset=iso-8859-1", []byte("caf\xe9")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewApiGwResponseWriter()
+			if test.contentType != "" {
+				w.Header().Set("Content-Type", test.contentType)
+			}
+			_, _ = w.Write(test.body)
+
+			response := w.GetResponse()
+			if !response.IsBase64Encoded {
+				t.Fatalf("body %q not base64 encoded", response.Body)
+			}
+			decoded, err := base64.StdEncoding.DecodeString(response.Body)
+			if err != nil || string(decoded) != string(test.body) {
+				t.Errorf("decoded body = %q, %v, want %q", decoded, err, test.body)
+			}
+		})
+	}
+}
+
+func TestIsTextContentType(t *testing.T) {
+	tests := map[string]bool{
+		"text/plain":                      true,
+		"text/html; charset=utf-8":        true,
+		"application/json":                true,
+		"application/problem+json":        true,
+		"application/atom+xml":            true,
+		"text/plain; charset=iso-8859-1":  false,
+		"application/octet-stream":        false,
+		"image/png":                       false,
+		"not a media type; charset=utf-8": false,
+	}
+	for contentType, want := range tests {
+		if got := IsTextContentType(contentType); got != want {
+			t.Errorf("IsTextContentType(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestResponseWriterMovesSetCookie(t *testing.T) {
+	w := NewApiGwResponseWriter()
+	w.Header().Add("Set-Cookie", "session=abc; HttpOnly")
+	w.Header().Add("Set-Cookie", "theme=dark, light")
+	w.WriteHeader(http.StatusNoContent)
+
+	response := w.GetResponse()
+	if _, ok := response.Headers["Set-Cookie"]; ok {
+		t.Errorf("Set-Cookie left in headers: %v", response.Headers)
+	}
+	if len(response.Cookies) != 2 || response.Cookies[0] != "session=abc; HttpOnly" || response.Cookies[1] != "theme=dark, light" {
+		t.Errorf("cookies = %q", response.Cookies)
+	}
+}
+
+func TestResponseWriterJoinsMultiValueHeaders(t *testing.T) {
+	w := NewApiGwResponseWriter()
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.WriteHeader(http.StatusNoContent)
+
+	if got := w.GetResponse().Headers["Vary"]; got != "Accept, Accept-Encoding" {
+		t.Errorf("Vary = %q, want %q", got, "Accept, Accept-Encoding")
+	}
+}
+
+func TestResponseWriterIgnoresSecondWriteHeader(t *testing.T) {
+	w := NewApiGwResponseWriter()
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write([]byte("created"))
+
+	if got := w.GetResponse().StatusCode; got != http.StatusCreated {
+		t.Errorf("status = %d, want %d", got, http.StatusCreated)
+	}
+}
+
+func TestResponseWriterWriteImpliesOk(t *testing.T) {
+	w := NewApiGwResponseWriter()
+	_, _ = w.Write([]byte("ok"))
+	w.WriteHeader(http.StatusTeapot)
+
+	response := w.GetResponse()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+	if response.Headers["Content-Type"] != "text/plain; charset=utf-8" || response.Body != "ok" {
+		t.Errorf("detected %q body %q", response.Headers["Content-Type"], response.Body)
+	}
+}
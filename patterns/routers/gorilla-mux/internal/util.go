@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,9 +17,18 @@ import (
 )
 
 type Response[T any] struct {
-	Body         *T
-	StatusCode   int
-	ErrorMessage *string
+	Body       *T
+	StatusCode int
+	Error      error
+	Header     http.Header
+}
+
+func (r *Response[T]) WithHeader(key, value string) *Response[T] {
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Set(key, value)
+	return r
 }
 
 type Request[T any] struct {
@@ -26,6 +36,12 @@ type Request[T any] struct {
 	Body        T
 	PathParams  map[string]string
 	QueryParams map[string]string
+	Header      http.Header
+}
+
+type ParamsRequest[T any, P any] struct {
+	*Request[T]
+	Params P
 }
 
 type Unit struct{}
@@ -72,16 +88,16 @@ func GetQueryParam[T any](r *Request[T], key string) string {
 	return value
 }
 
-func ErrorResponse[T any](err error, statusCode int) *Response[T] {
-	message := err.Error()
+func ErrorResponse[T any](err error) *Response[T] {
+	statusCode, _ := resolveError(err)
 	return &Response[T]{
-		StatusCode:   statusCode,
-		ErrorMessage: &message,
+		StatusCode: statusCode,
+		Error:      err,
 	}
 }
 
 func ServerError[T any](err error) *Response[T] {
-	return ErrorResponse[T](err, http.StatusInternalServerError)
+	return ErrorResponse[T](err)
 }
 
 func SuccessResponse[T any](body *T) *Response[T] {
@@ -91,12 +107,16 @@ func SuccessResponse[T any](body *T) *Response[T] {
 	}
 }
 
-func NotFoundResponse[T any]() *Response[T] {
+func NotModifiedResponse[T any]() *Response[T] {
 	return &Response[T]{
-		StatusCode: http.StatusNotFound,
+		StatusCode: http.StatusNotModified,
 	}
 }
 
+func NotFoundResponse[T any]() *Response[T] {
+	return ErrorResponse[T](NotFoundError)
+}
+
 func GetFieldsByTagValue(s any, tag, value string) []reflect.StructField {
 	t := reflect.TypeOf(s)
 	if t.Kind() == reflect.Ptr {
@@ -136,66 +156,131 @@ func GetQueryParams(r *http.Request) map[string]string {
 
 func RegisterFunc[I any, O any](router *mux.Router,
 	path, method string,
-	handler func(request *Request[I]) *Response[O]) *mux.Route {
-	return router.HandleFunc(path, toHandleFunc(handler)).Methods(method)
+	handler func(request *Request[I]) *Response[O],
+	middlewares ...Middleware) *mux.Route {
+	recordRoute(RouteInfo{
+		Method:       method,
+		Path:         path,
+		RequestType:  typeOf[I](),
+		ResponseType: typeOf[O](),
+	})
+	return router.Handle(path, Chain(toHandleFunc(handler), middlewares...)).Methods(method)
 }
 
 func RegisterFuncMatchContentType[I any, O any](router *mux.Router,
 	path, method string,
 	handler func(request *Request[I]) *Response[O],
-	contentType string) *mux.Route {
-	return RegisterFunc[I, O](router, path, method, handler).MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+	contentType string,
+	middlewares ...Middleware) *mux.Route {
+	recordRoute(RouteInfo{
+		Method:       method,
+		Path:         path,
+		RequestType:  typeOf[I](),
+		ResponseType: typeOf[O](),
+		ContentTypes: []string{contentType},
+	})
+	return router.Handle(path, Chain(toHandleFunc(handler), middlewares...)).Methods(method).MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
 		v := r.Header.Get("Content-Type")
 		return strings.EqualFold(v, contentType)
 	})
 }
 
+func RegisterParamsFunc[I any, P any, O any](router *mux.Router,
+	path, method string,
+	handler func(request *ParamsRequest[I, P]) *Response[O],
+	middlewares ...Middleware) *mux.Route {
+	recordRoute(RouteInfo{
+		Method:       method,
+		Path:         path,
+		RequestType:  typeOf[I](),
+		ResponseType: typeOf[O](),
+		ParamsType:   typeOf[P](),
+	})
+	return router.Handle(path, Chain(toParamsHandleFunc(handler), middlewares...)).Methods(method)
+}
+
 func IsUnit(value any) bool {
 	return reflect.TypeOf(value) == reflect.TypeOf(Unit{})
 }
 
-func toHandleFunc[I any, O any](handler func(request *Request[I]) *Response[O]) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var body I
-		if !IsUnit(body) {
-			err := json.NewDecoder(r.Body).Decode(&body)
-			if err != nil {
-				err = fmt.Errorf("failed to decode request body: %v", err)
-				w.WriteHeader(http.StatusBadRequest)
-				_, _ = w.Write([]byte(err.Error()))
-				return
-			}
+func decodeRequest[I any](w http.ResponseWriter, r *http.Request) (*Request[I], bool) {
+	var body I
+	if !IsUnit(body) {
+		err := json.NewDecoder(r.Body).Decode(&body)
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeError(w, r, NewApiError(PayloadTooLargeError, fmt.Sprintf("request body exceeds %d bytes", maxBytesError.Limit)))
+			return nil, false
 		}
-		response := handler(&Request[I]{
-			Context:     r.Context(),
-			Body:        body,
-			PathParams:  GetPathParams(r),
-			QueryParams: GetQueryParams(r),
-		})
-		if response.StatusCode < 100 || response.StatusCode >= 600 {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+		if err != nil {
+			writeError(w, r, NewApiError(ValidationError, "request body is not valid JSON").WithCause(err))
+			return nil, false
 		}
-		if response.ErrorMessage != nil {
-			w.WriteHeader(response.StatusCode)
-			_, _ = w.Write([]byte(*response.ErrorMessage))
+	}
+	return &Request[I]{
+		Context:     r.Context(),
+		Body:        body,
+		PathParams:  GetPathParams(r),
+		QueryParams: GetQueryParams(r),
+		Header:      r.Header,
+	}, true
+}
+
+func writeResponse[O any](w http.ResponseWriter, r *http.Request, response *Response[O]) {
+	if response.Error != nil {
+		writeError(w, r, response.Error)
+		return
+	}
+	if response.StatusCode < 100 || response.StatusCode >= 600 {
+		writeError(w, r, fmt.Errorf("handler returned invalid status code %d", response.StatusCode))
+		return
+	}
+	for key, values := range response.Header {
+		w.Header()[key] = values
+	}
+	if response.StatusCode == http.StatusNotModified {
+		w.WriteHeader(response.StatusCode)
+		return
+	}
+	if typeOf[O]() != typeOf[Unit]() {
+		responseBody, err := json.Marshal(response.Body)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("failed to encode response body: %w", err))
 			return
 		}
-		if !IsUnit(response.Body) {
-			responseBody, err := json.Marshal(response.Body)
-			if err != nil {
-				err = fmt.Errorf("failed to encode response body: %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				_, _ = w.Write([]byte(err.Error()))
-				return
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(response.StatusCode)
-			_, _ = w.Write(responseBody)
+		w.Header().Set("Content-Type", JsonContentType)
+		w.WriteHeader(response.StatusCode)
+		_, _ = w.Write(responseBody)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toHandleFunc[I any, O any](handler func(request *Request[I]) *Response[O]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, ok := decodeRequest[I](w, r)
+		if !ok {
 			return
 		}
+		writeResponse(w, r, handler(request))
+	}
+}
 
-		w.WriteHeader(http.StatusNoContent)
-		return
+func toParamsHandleFunc[I any, P any, O any](handler func(request *ParamsRequest[I, P]) *Response[O]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var params P
+		err := BindParams(r, &params)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		request, ok := decodeRequest[I](w, r)
+		if !ok {
+			return
+		}
+		writeResponse(w, r, handler(&ParamsRequest[I, P]{
+			Request: request,
+			Params:  params,
+		}))
 	}
 }
@@ -19,6 +19,8 @@ data "aws_iam_policy_document" "dynamodb" {
     actions = [
       "dynamodb:GetItem",
       "dynamodb:PutItem",
+      "dynamodb:UpdateItem",
+      "dynamodb:DeleteItem",
       "dynamodb:Scan"
     ]
     resources = [aws_dynamodb_table.table.arn]
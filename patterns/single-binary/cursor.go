@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var InvalidCursorError = errors.New("invalid cursor")
+var CursorScopeError = errors.New("cursor was issued for a different query")
+
+// cursorPayload carries the scope the key was issued for, a key only makes sense to the index and
+// filter that produced it.
+type cursorPayload struct {
+	Key       json.RawMessage `json:"k"`
+	Scope     string          `json:"s,omitempty"`
+	ExpiresAt int64           `json:"e,omitempty"`
+}
+
+type CursorCodec struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func newCursorCodec(secret []byte, ttl time.Duration) (*CursorCodec, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, sha256.Size)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &CursorCodec{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (c *CursorCodec) Encode(key map[string]types.AttributeValue, scope string) (*string, error) {
+	if key == nil {
+		return nil, nil
+	}
+	keyJson, err := attributevalue.MarshalMapJSON(key)
+	if err != nil {
+		return nil, err
+	}
+	payload := cursorPayload{Key: keyJson, Scope: scope}
+	if c.ttl > 0 {
+		payload.ExpiresAt = c.now().Add(c.ttl).Unix()
+	}
+	payloadJson, err := json.Marshal(&payload)
+	if err != nil {
+		return nil, err
+	}
+	cursor := base64.RawURLEncoding.EncodeToString(payloadJson) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(payloadJson))
+	return &cursor, nil
+}
+
+func (c *CursorCodec) Decode(cursor string, scope string) (map[string]types.AttributeValue, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, InvalidCursorError
+	}
+	payloadJson, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, InvalidCursorError
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, c.sign(payloadJson)) {
+		return nil, InvalidCursorError
+	}
+	var payload cursorPayload
+	if err = json.Unmarshal(payloadJson, &payload); err != nil {
+		return nil, InvalidCursorError
+	}
+	if payload.ExpiresAt != 0 && c.now().Unix() > payload.ExpiresAt {
+		return nil, InvalidCursorError
+	}
+	if payload.Scope != scope {
+		return nil, CursorScopeError
+	}
+	key, err := attributevalue.UnmarshalMapJSON(payload.Key)
+	if err != nil {
+		return nil, InvalidCursorError
+	}
+	return key, nil
+}
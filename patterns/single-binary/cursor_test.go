@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func testKey() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":          &types.AttributeValueMemberS{Value: "movie-1"},
+		"releaseDate": &types.AttributeValueMemberS{Value: "1999-03-31T00:00:00Z"},
+	}
+}
+
+func newTestCursorCodec(t *testing.T, secret string, ttl time.Duration) *CursorCodec {
+	t.Helper()
+	codec, err := newCursorCodec([]byte(secret), ttl)
+	if err != nil {
+		t.Fatalf("newCursorCodec: %v", err)
+	}
+	return codec
+}
+
+func encodeCursor(t *testing.T, codec *CursorCodec) string {
+	t.Helper()
+	cursor, err := codec.Encode(testKey(), "scope")
+	if err != nil || cursor == nil {
+		t.Fatalf("Encode = %v, %v", cursor, err)
+	}
+	return *cursor
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	codec := newTestCursorCodec(t, "secret", time.Minute)
+	key, err := codec.Decode(encodeCursor(t, codec), "scope")
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	for name, want := range testKey() {
+		got, ok := key[name].(*types.AttributeValueMemberS)
+		if !ok || got.Value != want.(*types.AttributeValueMemberS).Value {
+			t.Errorf("key[%s] = %#v, want %#v", name, key[name], want)
+		}
+	}
+}
+
+func TestCursorRejectsTamperedPayload(t *testing.T) {
+	codec := newTestCursorCodec(t, "secret", 0)
+	_, signature, _ := strings.Cut(encodeCursor(t, codec), ".")
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"k":{"id":{"S":"movie-2"}}}`))
+	if _, err := codec.Decode(payload+"."+signature, "scope"); !errors.Is(err, InvalidCursorError) {
+		t.Errorf("Decode = %v, want %v", err, InvalidCursorError)
+	}
+}
+
+func TestCursorRejectsTamperedSignature(t *testing.T) {
+	codec := newTestCursorCodec(t, "secret", 0)
+	payload, _, _ := strings.Cut(encodeCursor(t, codec), ".")
+	signature := base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	for _, cursor := range []string{payload + "." + signature, payload + ".", payload + ".!!", payload} {
+		if _, err := codec.Decode(cursor, "scope"); !errors.Is(err, InvalidCursorError) {
+			t.Errorf("Decode(%q) = %v, want %v", cursor, err, InvalidCursorError)
+		}
+	}
+}
+
+func TestCursorRejectsWrongSecret(t *testing.T) {
+	cursor := encodeCursor(t, newTestCursorCodec(t, "secret", 0))
+	other := newTestCursorCodec(t, "other-secret", 0)
+	if _, err := other.Decode(cursor, "scope"); !errors.Is(err, InvalidCursorError) {
+		t.Errorf("Decode = %v, want %v", err, InvalidCursorError)
+	}
+}
+
+func TestCursorExpires(t *testing.T) {
+	codec := newTestCursorCodec(t, "secret", time.Minute)
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	codec.now = func() time.Time { return now }
+	cursor := encodeCursor(t, codec)
+
+	now = now.Add(59 * time.Second)
+	if _, err := codec.Decode(cursor, "scope"); err != nil {
+		t.Errorf("Decode before expiry: %v", err)
+	}
+	now = now.Add(2 * time.Second)
+	if _, err := codec.Decode(cursor, "scope"); !errors.Is(err, InvalidCursorError) {
+		t.Errorf("Decode after expiry = %v, want %v", err, InvalidCursorError)
+	}
+}
+
+func TestCursorNilKey(t *testing.T) {
+	codec := newTestCursorCodec(t, "", 0)
+	cursor, err := codec.Encode(nil, "scope")
+	if cursor != nil || err != nil {
+		t.Errorf("Encode(nil) = %v, %v, want nil, nil", cursor, err)
+	}
+}
+
+func TestCursorRejectsOtherScope(t *testing.T) {
+	codec := newTestCursorCodec(t, "secret", 0)
+	if _, err := codec.Decode(encodeCursor(t, codec), "other-scope"); !errors.Is(err, CursorScopeError) {
+		t.Errorf("Decode = %v, want %v", err, CursorScopeError)
+	}
+}
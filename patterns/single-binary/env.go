@@ -1,11 +1,18 @@
 package main
 
 import (
+	"time"
+
 	"github.com/caarlos0/env"
 )
 
 type Environ struct {
-	MoviesTableArn string `env:"MOVIES_TABLE_ARN"`
+	MoviesTableArn string        `env:"MOVIES_TABLE_ARN"`
+	DispatchMode   string        `env:"DISPATCH_MODE" envDefault:"handler"`
+	CursorSecret   string        `env:"CURSOR_SECRET"`
+	CursorTtl      time.Duration `env:"CURSOR_TTL"`
+	DynamoDbUrl    string        `env:"DYNAMODB_ENDPOINT"`
+	Repository     string        `env:"MOVIE_REPOSITORY" envDefault:"dynamodb"`
 }
 
 var environ *Environ
@@ -1,33 +1,115 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"reflect"
 
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
+const (
+	DispatchModeHandler = "handler"
+	DispatchModeRouter  = "router"
+)
+
+type HandlerFunc func(ctx context.Context, request Request) (Response, error)
+
+type registration struct {
+	invoke Next
+	start  func()
+}
+
 type Handlers struct {
-	handlers map[string]interface{}
+	handlers    map[string]*registration
+	router      *Router
+	middlewares []Middleware
 }
 
-func newHandlers() *Handlers {
+func newHandlers(middlewares ...Middleware) *Handlers {
 	return &Handlers{
-		handlers: make(map[string]interface{}),
+		handlers:    make(map[string]*registration),
+		router:      newRouter(),
+		middlewares: middlewares,
 	}
 }
 
-func (h *Handlers) register(name string, handler interface{}) {
-	h.handlers[name] = handler
+func Register[Req any, Resp any](h *Handlers, name string, handler func(ctx context.Context, request Req) (Resp, error)) {
+	if _, ok := h.handlers[name]; ok {
+		panic(fmt.Sprintf("handler with name %s already registered", name))
+	}
+	var invoke Next = func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(Req)
+		if !ok {
+			return nil, fmt.Errorf("handler %s expects %s, got %T", name, reflect.TypeFor[Req](), request)
+		}
+		return handler(ctx, req)
+	}
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		invoke = h.middlewares[i](name, invoke)
+	}
+	h.handlers[name] = &registration{
+		invoke: invoke,
+		start: func() {
+			lambda.Start(typedInvoke[Req, Resp](name, invoke))
+		},
+	}
 }
 
-func (h *Handlers) run() {
-	name := os.Getenv("_HANDLER")
+func typedInvoke[Req any, Resp any](name string, invoke Next) func(ctx context.Context, request Req) (Resp, error) {
+	return func(ctx context.Context, request Req) (Resp, error) {
+		var resp Resp
+		out, err := invoke(ctx, request)
+		if out == nil {
+			return resp, err
+		}
+		resp, ok := out.(Resp)
+		if !ok {
+			return resp, fmt.Errorf("handler %s returned %T, expected %s", name, out, reflect.TypeFor[Resp]())
+		}
+		return resp, err
+	}
+}
+
+func RegisterRoute(h *Handlers, name, routeKey string, handler HandlerFunc) {
+	Register(h, name, handler)
+	h.router.add(routeKey, typedInvoke[Request, Response](name, h.handlers[name].invoke))
+}
+
+func RegisterBodyRoute[T any](h *Handlers, name, routeKey string, handler func(ctx context.Context, request Request, body *T) (Response, error)) {
+	rules := mustCompileRules[T]()
+	RegisterRoute(h, name, routeKey, func(ctx context.Context, request Request) (Response, error) {
+		body, problem := decodeAndValidate[T](&request, rules)
+		if problem != nil {
+			return *problem, nil
+		}
+		return handler(ctx, request, body)
+	})
+}
+
+func (h *Handlers) invoke(ctx context.Context, name string, request any) (any, error) {
 	handler, ok := h.handlers[name]
 	if !ok {
-		panic(fmt.Sprintf("no handler found with name %s", name))
+		return nil, fmt.Errorf("no handler found with name %s", name)
+	}
+	return handler.invoke(ctx, request)
+}
+
+func (h *Handlers) run(mode string) {
+	switch mode {
+	case DispatchModeRouter:
+		lambda.Start(h.router.dispatch)
+	case DispatchModeHandler:
+		name := os.Getenv("_HANDLER")
+		handler, ok := h.handlers[name]
+		if !ok {
+			panic(fmt.Sprintf("no handler found with name %s", name))
+		}
+		handler.start()
+	default:
+		panic(fmt.Sprintf("unknown dispatch mode %s", mode))
 	}
-	lambda.Start(handler)
 }
 
 var handlers *Handlers
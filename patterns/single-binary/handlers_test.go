@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func newTestHandlers() *Handlers {
+	return newHandlers(RequestIdMiddleware, LoggingMiddleware, TimingMiddleware, RecoveryMiddleware)
+}
+
+func requestWithId(id string) Request {
+	return Request{RequestContext: events.APIGatewayV2HTTPRequestContext{RequestID: id}}
+}
+
+func TestInvokePropagatesRequestId(t *testing.T) {
+	h := newTestHandlers()
+	var seen string
+	RegisterRoute(h, "Echo", "GET /echo", func(ctx context.Context, request Request) (Response, error) {
+		seen = requestIdFromContext(ctx)
+		return TextResponse(http.StatusOK, "ok"), nil
+	})
+
+	out, err := h.invoke(context.Background(), "Echo", requestWithId("request-1"))
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	response := out.(Response)
+	if seen != "request-1" {
+		t.Errorf("handler saw request id %q, want %q", seen, "request-1")
+	}
+	if got := response.Headers[RequestIdHeader]; got != "request-1" {
+		t.Errorf("%s header = %q, want %q", RequestIdHeader, got, "request-1")
+	}
+}
+
+func TestInvokeGeneratesRequestId(t *testing.T) {
+	h := newTestHandlers()
+	var seen string
+	RegisterRoute(h, "Echo", "GET /echo", func(ctx context.Context, request Request) (Response, error) {
+		seen = requestIdFromContext(ctx)
+		return TextResponse(http.StatusOK, "ok"), nil
+	})
+
+	out, err := h.invoke(context.Background(), "Echo", Request{})
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	header := out.(Response).Headers[RequestIdHeader]
+	if seen == "" || header != seen {
+		t.Errorf("generated request id: handler saw %q, header %q", seen, header)
+	}
+}
+
+func TestInvokeRecoversPanic(t *testing.T) {
+	h := newTestHandlers()
+	RegisterRoute(h, "Panic", "GET /panic", func(ctx context.Context, request Request) (Response, error) {
+		panic("boom")
+	})
+
+	out, err := h.invoke(context.Background(), "Panic", requestWithId("request-2"))
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	response := out.(Response)
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusInternalServerError)
+	}
+	if got := response.Headers[RequestIdHeader]; got != "request-2" {
+		t.Errorf("%s header = %q, want %q", RequestIdHeader, got, "request-2")
+	}
+}
+
+func TestInvokeRecoversPanicInNonHttpHandler(t *testing.T) {
+	h := newTestHandlers()
+	Register(h, "Panic", func(ctx context.Context, request string) (string, error) {
+		panic("boom")
+	})
+
+	_, err := h.invoke(context.Background(), "Panic", "payload")
+	if err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("err = %v, want panic error", err)
+	}
+}
+
+func TestInvokeTypeMismatch(t *testing.T) {
+	h := newTestHandlers()
+	Register(h, "Upper", func(ctx context.Context, request string) (string, error) {
+		return strings.ToUpper(request), nil
+	})
+
+	out, err := h.invoke(context.Background(), "Upper", "movie")
+	if err != nil || out != "MOVIE" {
+		t.Fatalf("invoke = %v, %v, want MOVIE", out, err)
+	}
+	_, err = h.invoke(context.Background(), "Upper", 42)
+	if err == nil || !strings.Contains(err.Error(), "expects string, got int") {
+		t.Errorf("err = %v, want type mismatch", err)
+	}
+}
+
+func TestInvokeUnknownHandler(t *testing.T) {
+	h := newTestHandlers()
+	_, err := h.invoke(context.Background(), "Missing", Request{})
+	if err == nil {
+		t.Error("expected error for unknown handler")
+	}
+}
+
+func TestTypedInvokeRejectsMismatchedResponse(t *testing.T) {
+	h := newHandlers(func(name string, next Next) Next {
+		return func(ctx context.Context, request any) (any, error) {
+			return 42, nil
+		}
+	})
+	RegisterRoute(h, "Echo", "GET /echo", func(ctx context.Context, request Request) (Response, error) {
+		return TextResponse(http.StatusOK, "ok"), nil
+	})
+
+	_, err := h.router.dispatch(context.Background(), Request{RouteKey: "GET /echo"})
+	if err == nil || !strings.Contains(err.Error(), "returned int") {
+		t.Errorf("err = %v, want response type mismatch", err)
+	}
+}
@@ -20,17 +20,29 @@ resource "aws_apigatewayv2_integration" "integration" {
 resource "aws_apigatewayv2_route" "get-movie-by-id" {
   api_id    = aws_apigatewayv2_api.api.id
   route_key = "GET /movies/{movieId}"
-  target    = "integrations/${aws_apigatewayv2_integration.integration["GetMovieById"].id}"
+  target    = "integrations/${aws_apigatewayv2_integration.integration[local.router ? "Router" : "GetMovieById"].id}"
 }
 
 resource "aws_apigatewayv2_route" "save-movie" {
   api_id    = aws_apigatewayv2_api.api.id
   route_key = "POST /movies"
-  target    = "integrations/${aws_apigatewayv2_integration.integration["SaveMovie"].id}"
+  target    = "integrations/${aws_apigatewayv2_integration.integration[local.router ? "Router" : "SaveMovie"].id}"
 }
 
 resource "aws_apigatewayv2_route" "list-movies" {
   api_id    = aws_apigatewayv2_api.api.id
   route_key = "GET /movies"
-  target    = "integrations/${aws_apigatewayv2_integration.integration["ListMovies"].id}"
+  target    = "integrations/${aws_apigatewayv2_integration.integration[local.router ? "Router" : "ListMovies"].id}"
+}
+
+resource "aws_apigatewayv2_route" "update-movie" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "PATCH /movies/{movieId}"
+  target    = "integrations/${aws_apigatewayv2_integration.integration[local.router ? "Router" : "UpdateMovie"].id}"
+}
+
+resource "aws_apigatewayv2_route" "delete-movie" {
+  api_id    = aws_apigatewayv2_api.api.id
+  route_key = "DELETE /movies/{movieId}"
+  target    = "integrations/${aws_apigatewayv2_integration.integration[local.router ? "Router" : "DeleteMovie"].id}"
 }
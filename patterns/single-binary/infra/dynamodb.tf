@@ -9,4 +9,37 @@ resource "aws_dynamodb_table" "movies" {
     name = "id"
     type = "S"
   }
-}
\ No newline at end of file
+
+  attribute {
+    name = "title"
+    type = "S"
+  }
+
+  attribute {
+    name = "catalog"
+    type = "S"
+  }
+
+  attribute {
+    name = "releaseDate"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name            = "title-index"
+    hash_key        = "title"
+    range_key       = "releaseDate"
+    projection_type = "ALL"
+    write_capacity  = 2
+    read_capacity   = 2
+  }
+
+  global_secondary_index {
+    name            = "releaseDate-index"
+    hash_key        = "catalog"
+    range_key       = "releaseDate"
+    projection_type = "ALL"
+    write_capacity  = 2
+    read_capacity   = 2
+  }
+}
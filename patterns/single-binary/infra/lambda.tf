@@ -1,9 +1,13 @@
 locals {
-  handlers = {
+  router = var.dispatch_mode == "router"
+  movie_handlers = {
     "GetMovieById" : 30
     "SaveMovie" : 30
     "ListMovies" : 30
+    "UpdateMovie" : 30
+    "DeleteMovie" : 30
   }
+  handlers = local.router ? { "Router" : 30 } : local.movie_handlers
 }
 
 data "aws_iam_policy_document" "assume_role" {
@@ -32,9 +36,15 @@ data "aws_iam_policy_document" "dynamodb" {
     actions = [
       "dynamodb:GetItem",
       "dynamodb:PutItem",
-      "dynamodb:Scan"
+      "dynamodb:UpdateItem",
+      "dynamodb:DeleteItem",
+      "dynamodb:Scan",
+      "dynamodb:Query"
+    ]
+    resources = [
+      aws_dynamodb_table.movies.arn,
+      "${aws_dynamodb_table.movies.arn}/index/*"
     ]
-    resources = [aws_dynamodb_table.movies.arn]
   }
 }
 
@@ -43,6 +53,11 @@ resource "aws_iam_role_policy" "dynamodb" {
   role   = aws_iam_role.role.id
 }
 
+resource "random_password" "cursor_secret" {
+  length  = 64
+  special = false
+}
+
 resource "aws_lambda_function" "function" {
   for_each         = local.handlers
   function_name    = "movies-${each.key}"
@@ -57,6 +72,9 @@ resource "aws_lambda_function" "function" {
   environment {
     variables = {
       MOVIES_TABLE_ARN : aws_dynamodb_table.movies.arn
+      DISPATCH_MODE : var.dispatch_mode
+      CURSOR_SECRET : random_password.cursor_secret.result
+      CURSOR_TTL : var.cursor_ttl
     }
   }
 }
@@ -4,6 +4,10 @@ terraform {
       source  = "hashicorp/aws"
       version = "6.25.0"
     }
+    random = {
+      source  = "hashicorp/random"
+      version = "3.7.2"
+    }
   }
   required_version = ">=1.11.2"
 }
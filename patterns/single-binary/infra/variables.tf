@@ -1,3 +1,17 @@
 variable "zip_path" {
   type = string
-}
\ No newline at end of file
+}
+
+variable "dispatch_mode" {
+  type    = string
+  default = "handler"
+  validation {
+    condition     = contains(["handler", "router"], var.dispatch_mode)
+    error_message = "dispatch_mode must be either handler or router"
+  }
+}
+
+variable "cursor_ttl" {
+  type    = string
+  default = "1h"
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+const LambdaRuntimeApiEnv = "AWS_LAMBDA_RUNTIME_API"
+
+func runningInLambda() bool {
+	return os.Getenv(LambdaRuntimeApiEnv) != ""
+}
+
+func toRequest(r *http.Request) (Request, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return Request{}, err
+	}
+	headers := make(map[string]string)
+	for key, values := range r.Header {
+		if strings.EqualFold(key, "Cookie") {
+			continue
+		}
+		headers[strings.ToLower(key)] = strings.Join(values, ",")
+	}
+	query := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		query[key] = strings.Join(values, ",")
+	}
+	var cookies []string
+	for _, cookie := range r.Cookies() {
+		cookies = append(cookies, cookie.String())
+	}
+	sourceIp, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIp = r.RemoteAddr
+	}
+	now := time.Now()
+	request := Request{
+		Version:               "2.0",
+		RouteKey:              "$default",
+		RawPath:               r.URL.EscapedPath(),
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: query,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:   "$default",
+			RequestID:  uuid.New().String(),
+			Stage:      "$default",
+			DomainName: r.Host,
+			Time:       now.Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:  now.UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIp,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+	if utf8.Valid(body) {
+		request.Body = string(body)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+	return request, nil
+}
+
+func writeResponse(w http.ResponseWriter, response Response) error {
+	for key, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	for key, value := range response.Headers {
+		w.Header().Set(key, value)
+	}
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			return err
+		}
+	}
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	_, err := w.Write(body)
+	return err
+}
+
+func internalServerError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write([]byte(`{"message":"Internal Server Error"}`))
+}
+
+func (h *Handlers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request, err := toRequest(r)
+	if err != nil {
+		slog.Error("Failed to read request", "error", err.Error())
+		internalServerError(w)
+		return
+	}
+	response, err := h.router.dispatch(r.Context(), request)
+	if err != nil {
+		slog.Error("Handler failed", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+		internalServerError(w)
+		return
+	}
+	if err = writeResponse(w, response); err != nil {
+		slog.Error("Failed to write response", "error", err.Error())
+	}
+}
+
+func (h *Handlers) serveLocal(addr string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{
+		Addr:    addr,
+		Handler: h,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	slog.Info("Starting local server", "addr", addr)
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		panic(err)
+	}
+}
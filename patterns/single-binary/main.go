@@ -2,28 +2,63 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+)
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+const (
+	RepositoryDynamoDb = "dynamodb"
+	RepositoryMemory   = "memory"
 )
 
+func newMovieRepository(ctx context.Context, environ *Environ) (MovieRepository, error) {
+	switch environ.Repository {
+	case RepositoryDynamoDb:
+		client, err := newDynamoDbClient(ctx, environ.DynamoDbUrl)
+		if err != nil {
+			return nil, err
+		}
+		return newDynamoMovieRepository(client, environ.MoviesTableArn), nil
+	case RepositoryMemory:
+		return newInMemoryMovieRepository(), nil
+	}
+	return nil, fmt.Errorf("unknown repository %s", environ.Repository)
+}
+
 func init() {
 	var err error
 	environ, err = loadEnv()
 	if err != nil {
 		panic(err)
 	}
-	handlers = newHandlers()
-	handlers.register("GetMovieById", GetMovieById)
-	handlers.register("SaveMovie", SaveMovie)
-	handlers.register("ListMovies", ListMovies)
-	cfg, err := config.LoadDefaultConfig(context.Background())
+	repository, err := newMovieRepository(context.Background(), environ)
 	if err != nil {
 		panic(err)
 	}
-	dynamodbClient = dynamodb.NewFromConfig(cfg)
+	if environ.CursorSecret == "" {
+		slog.Warn("CURSOR_SECRET not set, cursors will only be valid within this instance")
+	}
+	cursors, err := newCursorCodec([]byte(environ.CursorSecret), environ.CursorTtl)
+	if err != nil {
+		panic(err)
+	}
+	movies := newMoviesService(repository, cursors)
+	handlers = newHandlers(RequestIdMiddleware, LoggingMiddleware, TimingMiddleware, RecoveryMiddleware)
+	RegisterRoute(handlers, "GetMovieById", "GET /movies/{movieId}", movies.GetMovieById)
+	RegisterBodyRoute(handlers, "SaveMovie", "POST /movies", movies.SaveMovie)
+	RegisterRoute(handlers, "ListMovies", "GET /movies", movies.ListMovies)
+	RegisterBodyRoute(handlers, "UpdateMovie", "PATCH /movies/{movieId}", movies.UpdateMovie)
+	RegisterRoute(handlers, "DeleteMovie", "DELETE /movies/{movieId}", movies.DeleteMovie)
 }
 
 func main() {
-	handlers.run()
+	local := flag.Bool("local", false, "serve all handlers over HTTP instead of the Lambda runtime")
+	addr := flag.String("addr", ":8080", "listen address of the local HTTP server")
+	flag.Parse()
+	if *local || !runningInLambda() {
+		handlers.serveLocal(*addr)
+		return
+	}
+	handlers.run(environ.DispatchMode)
 }
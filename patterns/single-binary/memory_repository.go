@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type InMemoryMovieRepository struct {
+	mutex  sync.RWMutex
+	movies map[string]MovieRecord
+}
+
+func newInMemoryMovieRepository() *InMemoryMovieRepository {
+	return &InMemoryMovieRepository{
+		movies: make(map[string]MovieRecord),
+	}
+}
+
+func (r *InMemoryMovieRepository) Get(_ context.Context, id string) (*MovieRecord, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	record, ok := r.movies[id]
+	if !ok {
+		return nil, MovieNotFoundError
+	}
+	return &record, nil
+}
+
+func (r *InMemoryMovieRepository) Put(_ context.Context, record *MovieRecord) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.movies[record.Id]; ok {
+		return MovieConflictError
+	}
+	r.movies[record.Id] = *record
+	return nil
+}
+
+func (r *InMemoryMovieRepository) matches(filter *MovieFilter, record *MovieRecord) bool {
+	if filter.Title != nil && record.Title != *filter.Title {
+		return false
+	}
+	from, to := filter.releaseDateBounds()
+	releaseDate := record.ReleaseDate.UTC().Format(filterDateLayout)
+	return releaseDate >= from && releaseDate < to
+}
+
+func cursorRecord(key map[string]types.AttributeValue) (MovieRecord, bool) {
+	var record MovieRecord
+	id, ok := key["id"].(*types.AttributeValueMemberS)
+	if !ok {
+		return record, false
+	}
+	record.Id = id.Value
+	if releaseDate, ok := key["releaseDate"]; ok {
+		if err := attributevalue.Unmarshal(releaseDate, &record.ReleaseDate); err != nil {
+			return record, false
+		}
+	}
+	return record, true
+}
+
+func (r *InMemoryMovieRepository) List(_ context.Context, filter MovieFilter) (*MoviePage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	var records []MovieRecord
+	for _, record := range r.movies {
+		if !filter.hasFilter() || r.matches(&filter, &record) {
+			records = append(records, record)
+		}
+	}
+	compare := func(a, b MovieRecord) int {
+		if filter.hasFilter() {
+			if c := a.ReleaseDate.Compare(b.ReleaseDate); c != 0 {
+				if filter.Descending {
+					return -c
+				}
+				return c
+			}
+		}
+		return strings.Compare(a.Id, b.Id)
+	}
+	slices.SortFunc(records, compare)
+	if start, ok := cursorRecord(filter.StartKey); ok {
+		// The cursor record may have been deleted since, so resume after its position rather than its index.
+		index, found := slices.BinarySearchFunc(records, start, compare)
+		if found {
+			index++
+		}
+		records = records[index:]
+	}
+	page := &MoviePage{
+		Movies: records,
+	}
+	if int32(len(records)) > limit {
+		page.Movies = records[:limit]
+		last := page.Movies[limit-1]
+		page.LastKey = primaryKey(last.Id)
+		if filter.hasFilter() {
+			releaseDate, err := attributevalue.Marshal(last.ReleaseDate)
+			if err != nil {
+				return nil, err
+			}
+			page.LastKey["releaseDate"] = releaseDate
+		}
+	}
+	return page, nil
+}
+
+func (r *InMemoryMovieRepository) Update(_ context.Context, id string, update MovieUpdate) (*MovieRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	record, ok := r.movies[id]
+	if !ok {
+		return nil, MovieNotFoundError
+	}
+	if record.Version != update.Version {
+		return nil, MovieConflictError
+	}
+	if update.Title != nil {
+		record.Title = *update.Title
+	}
+	if update.ReleaseDate != nil {
+		record.ReleaseDate = update.ReleaseDate.UTC()
+	}
+	record.Version++
+	r.movies[id] = record
+	return &record, nil
+}
+
+func (r *InMemoryMovieRepository) Delete(_ context.Context, id string, version *int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	record, ok := r.movies[id]
+	if !ok {
+		return MovieNotFoundError
+	}
+	if version != nil && record.Version != *version {
+		return MovieConflictError
+	}
+	delete(r.movies, id)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/google/uuid"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+type Next func(ctx context.Context, request any) (any, error)
+
+type Middleware func(name string, next Next) Next
+
+type requestIdKey struct{}
+
+func requestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+func RequestIdMiddleware(_ string, next Next) Next {
+	return func(ctx context.Context, request any) (any, error) {
+		var id string
+		if req, ok := request.(Request); ok {
+			id = req.RequestContext.RequestID
+		}
+		if lc, ok := lambdacontext.FromContext(ctx); ok && id == "" {
+			id = lc.AwsRequestID
+		}
+		if id == "" {
+			id = uuid.New().String()
+		}
+		out, err := next(context.WithValue(ctx, requestIdKey{}, id), request)
+		if response, ok := out.(Response); ok {
+			if response.Headers == nil {
+				response.Headers = make(map[string]string)
+			}
+			response.Headers[RequestIdHeader] = id
+			out = response
+		}
+		return out, err
+	}
+}
+
+func LoggingMiddleware(name string, next Next) Next {
+	return func(ctx context.Context, request any) (any, error) {
+		requestId := requestIdFromContext(ctx)
+		slog.Info("Invoking handler", "handler", name, "requestId", requestId)
+		out, err := next(ctx, request)
+		if err != nil {
+			slog.Error("Handler failed", "handler", name, "requestId", requestId, "error", err.Error())
+			return out, err
+		}
+		if response, ok := out.(Response); ok {
+			slog.Info("Handler finished", "handler", name, "requestId", requestId, "statusCode", response.StatusCode)
+		}
+		return out, err
+	}
+}
+
+func TimingMiddleware(name string, next Next) Next {
+	return func(ctx context.Context, request any) (any, error) {
+		start := time.Now()
+		out, err := next(ctx, request)
+		slog.Info("Handler timing", "handler", name, "requestId", requestIdFromContext(ctx),
+			"duration", time.Since(start).String())
+		return out, err
+	}
+}
+
+func RecoveryMiddleware(name string, next Next) Next {
+	return func(ctx context.Context, request any) (out any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("Handler panicked", "handler", name, "requestId", requestIdFromContext(ctx),
+					"panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+				if _, ok := request.(Request); ok {
+					out = InternalServerError(fmt.Errorf("handler %s panicked", name))
+					err = nil
+					return
+				}
+				out = nil
+				err = fmt.Errorf("handler %s panicked: %v", name, r)
+			}
+		}()
+		return next(ctx, request)
+	}
+}
@@ -2,33 +2,47 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 )
 
 type SaveMovieRequest struct {
-	Title       string    `json:"title"`
-	ReleaseDate time.Time `json:"releaseDate"`
+	Title       string    `json:"title" validate:"required,max=256"`
+	ReleaseDate time.Time `json:"releaseDate" validate:"required,after=1888-01-01,before=2100-01-01"`
+}
+
+type UpdateMovieRequest struct {
+	Title       *string    `json:"title,omitempty" validate:"min=1,max=256"`
+	ReleaseDate *time.Time `json:"releaseDate,omitempty" validate:"after=1888-01-01,before=2100-01-01"`
+	Version     *int64     `json:"version" validate:"required,min=0"`
+}
+
+func (r *UpdateMovieRequest) Validate() []FieldError {
+	if r.Title == nil && r.ReleaseDate == nil {
+		return []FieldError{{Message: "at least one of title, releaseDate is required"}}
+	}
+	return nil
 }
 
 type MovieRecord struct {
 	Id          string    `dynamodbav:"id,string,pk"`
 	Title       string    `dynamodbav:"title"`
 	ReleaseDate time.Time `dynamodbav:"releaseDate"`
+	Version     int64     `dynamodbav:"version"`
+	Catalog     string    `dynamodbav:"catalog"`
 }
 
 type GetMovieResponse struct {
 	Id          string    `json:"id"`
 	Title       string    `json:"title"`
 	ReleaseDate time.Time `json:"releaseDate"`
+	Version     int64     `json:"version"`
 }
 
 type ListMoviesResponse struct {
@@ -36,145 +50,213 @@ type ListMoviesResponse struct {
 	LastEvaluatedKey *string            `json:"LastEvaluatedKey,omitempty"`
 }
 
-func primaryKey(id string) map[string]types.AttributeValue {
-	out, _ := attributevalue.MarshalMap(map[string]string{
-		"id": id,
-	})
-	return out
+type MoviesService struct {
+	repository MovieRepository
+	cursors    *CursorCodec
 }
 
-func GetMovieById(ctx context.Context, request Request) (Response, error) {
-	movieId, ok := request.PathParameters["movieId"]
-	if !ok {
-		return Response{
-			StatusCode: http.StatusBadRequest,
-			Body:       "movieId path param expected",
-		}, nil
+func newMoviesService(repository MovieRepository, cursors *CursorCodec) *MoviesService {
+	return &MoviesService{
+		repository: repository,
+		cursors:    cursors,
 	}
-	slog.Info("Fetching movie", "movieId", movieId)
-	out, err := dynamodbClient.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(environ.MoviesTableArn),
-		Key:       primaryKey(movieId),
-	})
-	if err != nil {
-		return InternalServerError(err), nil
-	}
-	if out.Item == nil || len(out.Item) == 0 {
-		return Response{
-			StatusCode: http.StatusNotFound,
-		}, nil
-	}
-	var record MovieRecord
-	err = attributevalue.UnmarshalMap(out.Item, &record)
-	if err != nil {
-		return InternalServerError(err), nil
-	}
-	response := &GetMovieResponse{
+}
+
+func (GetMovieResponse) CsvHeader() []string {
+	return []string{"id", "title", "releaseDate", "version"}
+}
+
+func (m GetMovieResponse) CsvRow() []string {
+	return []string{m.Id, m.Title, m.ReleaseDate.Format(time.RFC3339), strconv.FormatInt(m.Version, 10)}
+}
+
+func toMovieResponse(record *MovieRecord) GetMovieResponse {
+	return GetMovieResponse{
 		Id:          record.Id,
 		Title:       record.Title,
 		ReleaseDate: record.ReleaseDate,
+		Version:     record.Version,
 	}
-	responseBody, err := json.Marshal(response)
-	if err != nil {
-		return InternalServerError(err), nil
+}
+
+func repositoryError(err error) Response {
+	if errors.Is(err, MovieNotFoundError) {
+		return ProblemResponse(http.StatusNotFound, "movie not found", nil)
 	}
-	return Response{
-		StatusCode: 200,
-		Body:       string(responseBody),
-	}, nil
+	if errors.Is(err, MovieConflictError) {
+		return ProblemResponse(http.StatusConflict, MovieConflictError.Error(), nil)
+	}
+	return InternalServerError(err)
 }
 
-func SaveMovie(ctx context.Context, request Request) (Response, error) {
-	slog.Info("Received", "headers", request.Headers)
-	contentType := getHeader(request.Headers, "Content-Type")
-	if contentType == nil || *contentType != "application/json" {
-		return Response{
-			StatusCode: http.StatusBadRequest,
-			Body:       "application/json Content-Type expected",
-		}, nil
-	}
-	body, err := unmarshallBody[SaveMovieRequest](&request)
+func movieIdMissing() Response {
+	return ProblemResponse(http.StatusBadRequest, "invalid path parameters", []FieldError{
+		{Field: "movieId", Message: "is required"},
+	})
+}
+
+func (s *MoviesService) GetMovieById(ctx context.Context, request Request) (Response, error) {
+	movieId, ok := request.PathParameters["movieId"]
+	if !ok {
+		return movieIdMissing(), nil
+	}
+	slog.Info("Fetching movie", "movieId", movieId)
+	record, err := s.repository.Get(ctx, movieId)
 	if err != nil {
-		return Response{StatusCode: http.StatusBadRequest}, nil
+		return repositoryError(err), nil
 	}
+	return JsonResponse(http.StatusOK, toMovieResponse(record)), nil
+}
+
+func (s *MoviesService) SaveMovie(ctx context.Context, request Request, body *SaveMovieRequest) (Response, error) {
+	slog.Info("Received", "headers", request.Headers)
 	record := &MovieRecord{
 		Id:          uuid.New().String(),
 		Title:       body.Title,
-		ReleaseDate: body.ReleaseDate,
+		ReleaseDate: body.ReleaseDate.UTC(),
+		Version:     1,
+		Catalog:     MovieCatalog,
 	}
-	out, err := attributevalue.MarshalMap(record)
+	err := s.repository.Put(ctx, record)
 	if err != nil {
-		return InternalServerError(err), nil
+		return repositoryError(err), nil
 	}
-	_, err = dynamodbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(environ.MoviesTableArn),
-		Item:      out,
-	})
-	if err != nil {
-		return InternalServerError(err), nil
+	return JsonResponse(http.StatusOK, toMovieResponse(record)), nil
+}
+
+func parseFilterDate(query map[string]string, name string, fieldErrors *[]FieldError) *time.Time {
+	value := getOptional(query, name)
+	if value == nil {
+		return nil
 	}
-	responseBody, err := json.Marshal(record)
+	date, err := time.Parse(filterDateLayout, *value)
 	if err != nil {
-		return InternalServerError(err), nil
+		*fieldErrors = append(*fieldErrors, FieldError{Field: name, Message: "must be a YYYY-MM-DD date"})
+		return nil
 	}
-	return Response{
-		StatusCode: http.StatusOK,
-		Body:       string(responseBody),
-	}, nil
+	return &date
 }
 
-func ListMovies(ctx context.Context, request Request) (Response, error) {
-	exclusiveStartKeyParam := getOptional(request.QueryStringParameters, "exclusiveStartKey")
-	limitParam := getOptional(request.QueryStringParameters, "limit")
-	var limit *int32
-	if limitParam != nil {
-		val, err := parseInt32(*limitParam)
-		if err != nil {
-			return Response{StatusCode: http.StatusBadRequest}, nil
+func parseMovieFilter(query map[string]string) (*MovieFilter, []FieldError) {
+	var fieldErrors []FieldError
+	filter := &MovieFilter{
+		Title: getOptional(query, "title"),
+		From:  parseFilterDate(query, "from", &fieldErrors),
+		To:    parseFilterDate(query, "to", &fieldErrors),
+		Limit: DefaultPageLimit,
+	}
+	if yearParam := getOptional(query, "year"); yearParam != nil {
+		year, err := strconv.Atoi(*yearParam)
+		if err != nil || year < 1 || year > 9999 {
+			fieldErrors = append(fieldErrors, FieldError{Field: "year", Message: "must be a year"})
+		} else {
+			from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+			to := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+			if filter.From == nil || filter.From.Before(from) {
+				filter.From = &from
+			}
+			if filter.To == nil || filter.To.After(to) {
+				filter.To = &to
+			}
 		}
-		limit = &val
 	}
-	var exclusiveStartKey map[string]types.AttributeValue
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "from", Message: "must not be after to"})
+	}
+	if limitParam := getOptional(query, "limit"); limitParam != nil {
+		limit, err := parseInt32(*limitParam)
+		if err != nil || limit < 1 || limit > MaxPageLimit {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "limit",
+				Message: fmt.Sprintf("must be a number between 1 and %d", MaxPageLimit),
+			})
+		} else {
+			filter.Limit = limit
+		}
+	}
+	if orderParam := getOptional(query, "order"); orderParam != nil {
+		filter.Ordered = true
+		switch *orderParam {
+		case "asc":
+		case "desc":
+			filter.Descending = true
+		default:
+			fieldErrors = append(fieldErrors, FieldError{Field: "order", Message: "must be asc or desc"})
+		}
+	}
+	return filter, fieldErrors
+}
+
+func (s *MoviesService) ListMovies(ctx context.Context, request Request) (Response, error) {
+	filter, fieldErrors := parseMovieFilter(request.QueryStringParameters)
+	if len(fieldErrors) > 0 {
+		return ProblemResponse(http.StatusBadRequest, "invalid query parameters", fieldErrors), nil
+	}
+	exclusiveStartKeyParam := getOptional(request.QueryStringParameters, "exclusiveStartKey")
 	if exclusiveStartKeyParam != nil {
 		var err error
-		exclusiveStartKey, err = attributevalue.UnmarshalMapJSON([]byte(*exclusiveStartKeyParam))
+		filter.StartKey, err = s.cursors.Decode(*exclusiveStartKeyParam, filter.fingerprint())
+		if errors.Is(err, CursorScopeError) {
+			return ProblemResponse(http.StatusBadRequest, "exclusiveStartKey was issued for a different query", nil), nil
+		}
 		if err != nil {
-			return InternalServerError(err), nil
+			return ProblemResponse(http.StatusBadRequest, "exclusiveStartKey is not a valid cursor", nil), nil
 		}
 	}
-	out, err := dynamodbClient.Scan(ctx, &dynamodb.ScanInput{
-		TableName:         aws.String(environ.MoviesTableArn),
-		Limit:             limit,
-		ExclusiveStartKey: exclusiveStartKey,
-	})
+	page, err := s.repository.List(ctx, *filter)
 	if err != nil {
 		return InternalServerError(err), nil
 	}
-	lastEvaluatedKey, err := convertLastEvaluatedKey(out.LastEvaluatedKey)
+	lastEvaluatedKey, err := s.cursors.Encode(page.LastKey, filter.fingerprint())
 	if err != nil {
 		return InternalServerError(err), nil
 	}
 	var result ListMoviesResponse
 	result.LastEvaluatedKey = lastEvaluatedKey
-	result.Movies = make([]GetMovieResponse, len(out.Items))
-	for i, item := range out.Items {
-		var record MovieRecord
-		err = attributevalue.UnmarshalMap(item, &record)
-		if err != nil {
-			return InternalServerError(err), nil
-		}
-		result.Movies[i] = GetMovieResponse{
-			Id:          record.Id,
-			Title:       record.Title,
-			ReleaseDate: record.ReleaseDate,
+	result.Movies = make([]GetMovieResponse, len(page.Movies))
+	for i := range page.Movies {
+		result.Movies[i] = toMovieResponse(&page.Movies[i])
+	}
+	return ListResponse(&request, result.Movies, &result, lastEvaluatedKey), nil
+}
+
+func (s *MoviesService) UpdateMovie(ctx context.Context, request Request, body *UpdateMovieRequest) (Response, error) {
+	movieId, ok := request.PathParameters["movieId"]
+	if !ok {
+		return movieIdMissing(), nil
+	}
+	slog.Info("Updating movie", "movieId", movieId, "version", *body.Version)
+	record, err := s.repository.Update(ctx, movieId, MovieUpdate{
+		Title:       body.Title,
+		ReleaseDate: body.ReleaseDate,
+		Version:     *body.Version,
+	})
+	if err != nil {
+		return repositoryError(err), nil
+	}
+	return JsonResponse(http.StatusOK, toMovieResponse(record)), nil
+}
+
+func (s *MoviesService) DeleteMovie(ctx context.Context, request Request) (Response, error) {
+	movieId, ok := request.PathParameters["movieId"]
+	if !ok {
+		return movieIdMissing(), nil
+	}
+	var version *int64
+	versionParam := getOptional(request.QueryStringParameters, "version")
+	if versionParam != nil {
+		value, err := strconv.ParseInt(*versionParam, 10, 64)
+		if err != nil || value < 0 {
+			return ProblemResponse(http.StatusBadRequest, "invalid query parameters", []FieldError{
+				{Field: "version", Message: "must be a non-negative number"},
+			}), nil
 		}
+		version = &value
 	}
-	responseBody, err := json.Marshal(&result)
+	slog.Info("Deleting movie", "movieId", movieId)
+	err := s.repository.Delete(ctx, movieId, version)
 	if err != nil {
-		return InternalServerError(err), nil
+		return repositoryError(err), nil
 	}
-	return Response{
-		StatusCode: http.StatusOK,
-		Body:       string(responseBody),
-	}, nil
+	return Response{StatusCode: http.StatusNoContent}, nil
 }
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func seedMovies(t *testing.T, repository MovieRepository) {
+	t.Helper()
+	for i, id := range []string{"a", "b", "c"} {
+		err := repository.Put(context.Background(), &MovieRecord{
+			Id:          id,
+			Title:       "Movie " + id,
+			ReleaseDate: time.Date(2000+i, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Version:     1,
+			Catalog:     MovieCatalog,
+		})
+		if err != nil {
+			t.Fatalf("seed %s: %v", id, err)
+		}
+	}
+}
+
+func newTestMovieHandlers(t *testing.T) (*Handlers, MovieRepository) {
+	t.Helper()
+	repository := newInMemoryMovieRepository()
+	seedMovies(t, repository)
+	cursors := newTestCursorCodec(t, "secret", 0)
+	movies := newMoviesService(repository, cursors)
+	h := newTestHandlers()
+	RegisterRoute(h, "GetMovieById", "GET /movies/{movieId}", movies.GetMovieById)
+	RegisterBodyRoute(h, "SaveMovie", "POST /movies", movies.SaveMovie)
+	RegisterRoute(h, "ListMovies", "GET /movies", movies.ListMovies)
+	RegisterBodyRoute(h, "UpdateMovie", "PATCH /movies/{movieId}", movies.UpdateMovie)
+	RegisterRoute(h, "DeleteMovie", "DELETE /movies/{movieId}", movies.DeleteMovie)
+	return h, repository
+}
+
+type movieRequest struct {
+	method  string
+	path    string
+	query   map[string]string
+	headers map[string]string
+	body    string
+}
+
+func (r movieRequest) toRequest() Request {
+	headers := r.headers
+	if headers == nil && r.body != "" {
+		headers = map[string]string{"Content-Type": ContentTypeJson}
+	}
+	return Request{
+		RawPath:               r.path,
+		QueryStringParameters: r.query,
+		Headers:               headers,
+		Body:                  r.body,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: r.method},
+		},
+	}
+}
+
+func dispatchMovie(t *testing.T, h *Handlers, request movieRequest) Response {
+	t.Helper()
+	response, err := h.router.dispatch(context.Background(), request.toRequest())
+	if err != nil {
+		t.Fatalf("dispatch %s %s: %v", request.method, request.path, err)
+	}
+	return response
+}
+
+func TestMovieHandlerStatusCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     movieRequest
+		status      int
+		contentType string
+	}{
+		{"get", movieRequest{method: "GET", path: "/movies/a"}, http.StatusOK, ContentTypeJson},
+		{"get missing", movieRequest{method: "GET", path: "/movies/z"}, http.StatusNotFound, ProblemContentType},
+		{"get wrong method", movieRequest{method: "PUT", path: "/movies/a"}, http.StatusMethodNotAllowed, ""},
+
+		{"save", movieRequest{method: "POST", path: "/movies", body: `{"title":"New","releaseDate":"2020-05-01T00:00:00Z"}`}, http.StatusOK, ContentTypeJson},
+		{"save without content type", movieRequest{method: "POST", path: "/movies", headers: map[string]string{}, body: `{}`}, http.StatusBadRequest, ProblemContentType},
+		{"save malformed", movieRequest{method: "POST", path: "/movies", body: `{`}, http.StatusBadRequest, ProblemContentType},
+		{"save invalid", movieRequest{method: "POST", path: "/movies", body: `{"title":"","releaseDate":"1800-01-01T00:00:00Z"}`}, http.StatusBadRequest, ProblemContentType},
+
+		{"list", movieRequest{method: "GET", path: "/movies"}, http.StatusOK, ContentTypeJson},
+		{"list csv", movieRequest{method: "GET", path: "/movies", headers: map[string]string{"Accept": "text/csv"}}, http.StatusOK, ContentTypeCsv + "; charset=utf-8"},
+		{"list not acceptable", movieRequest{method: "GET", path: "/movies", headers: map[string]string{"Accept": "image/png"}}, http.StatusNotAcceptable, ""},
+		{"list invalid limit", movieRequest{method: "GET", path: "/movies", query: map[string]string{"limit": "0"}}, http.StatusBadRequest, ProblemContentType},
+		{"list invalid order", movieRequest{method: "GET", path: "/movies", query: map[string]string{"order": "up"}}, http.StatusBadRequest, ProblemContentType},
+		{"list invalid cursor", movieRequest{method: "GET", path: "/movies", query: map[string]string{"exclusiveStartKey": "bogus"}}, http.StatusBadRequest, ProblemContentType},
+		{"list wrong method", movieRequest{method: "PUT", path: "/movies"}, http.StatusMethodNotAllowed, ""},
+
+		{"update", movieRequest{method: "PATCH", path: "/movies/a", body: `{"title":"Renamed","version":1}`}, http.StatusOK, ContentTypeJson},
+		{"update stale version", movieRequest{method: "PATCH", path: "/movies/a", body: `{"title":"Renamed","version":2}`}, http.StatusConflict, ProblemContentType},
+		{"update missing", movieRequest{method: "PATCH", path: "/movies/z", body: `{"title":"Renamed","version":1}`}, http.StatusNotFound, ProblemContentType},
+		{"update without fields", movieRequest{method: "PATCH", path: "/movies/a", body: `{"version":1}`}, http.StatusBadRequest, ProblemContentType},
+		{"update without version", movieRequest{method: "PATCH", path: "/movies/a", body: `{"title":"Renamed"}`}, http.StatusBadRequest, ProblemContentType},
+
+		{"delete", movieRequest{method: "DELETE", path: "/movies/a"}, http.StatusNoContent, ""},
+		{"delete with version", movieRequest{method: "DELETE", path: "/movies/a", query: map[string]string{"version": "1"}}, http.StatusNoContent, ""},
+		{"delete stale version", movieRequest{method: "DELETE", path: "/movies/a", query: map[string]string{"version": "2"}}, http.StatusConflict, ProblemContentType},
+		{"delete invalid version", movieRequest{method: "DELETE", path: "/movies/a", query: map[string]string{"version": "one"}}, http.StatusBadRequest, ProblemContentType},
+		{"delete missing", movieRequest{method: "DELETE", path: "/movies/z"}, http.StatusNotFound, ProblemContentType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, _ := newTestMovieHandlers(t)
+			response := dispatchMovie(t, h, test.request)
+			if response.StatusCode != test.status {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, test.status, response.Body)
+			}
+			if test.contentType != "" && response.Headers["Content-Type"] != test.contentType {
+				t.Errorf("Content-Type = %q, want %q", response.Headers["Content-Type"], test.contentType)
+			}
+		})
+	}
+}
+
+func TestMovieHandlersRequireMovieId(t *testing.T) {
+	h, _ := newTestMovieHandlers(t)
+	for _, name := range []string{"GetMovieById", "UpdateMovie", "DeleteMovie"} {
+		out, err := h.invoke(context.Background(), name, Request{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		response := out.(Response)
+		if response.StatusCode != http.StatusBadRequest || response.Headers["Content-Type"] != ProblemContentType {
+			t.Errorf("%s = %d %q, want 400 problem", name, response.StatusCode, response.Headers["Content-Type"])
+		}
+	}
+}
+
+func listMovieIds(t *testing.T, h *Handlers, query map[string]string) ([]string, *string) {
+	t.Helper()
+	response := dispatchMovie(t, h, movieRequest{method: "GET", path: "/movies", query: query})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("list status = %d (body %s)", response.StatusCode, response.Body)
+	}
+	var result ListMoviesResponse
+	if err := json.Unmarshal([]byte(response.Body), &result); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	ids := make([]string, len(result.Movies))
+	for i, movie := range result.Movies {
+		ids[i] = movie.Id
+	}
+	return ids, result.LastEvaluatedKey
+}
+
+func TestListResumesAfterCursorMovieIsDeleted(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  map[string]string
+		first  []string
+		second []string
+	}{
+		{"by id", map[string]string{}, []string{"a", "b"}, []string{"c"}},
+		{"by release date descending", map[string]string{"order": "desc"}, []string{"c", "b"}, []string{"a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, repository := newTestMovieHandlers(t)
+			query := map[string]string{"limit": "2"}
+			for key, value := range test.query {
+				query[key] = value
+			}
+			ids, cursor := listMovieIds(t, h, query)
+			if !slices.Equal(ids, test.first) || cursor == nil {
+				t.Fatalf("page 1 = %v, cursor %v, want %v", ids, cursor, test.first)
+			}
+			if err := repository.Delete(context.Background(), "b", nil); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			query["exclusiveStartKey"] = *cursor
+			ids, cursor = listMovieIds(t, h, query)
+			if !slices.Equal(ids, test.second) || cursor != nil {
+				t.Errorf("page 2 = %v, cursor %v, want %v", ids, cursor, test.second)
+			}
+		})
+	}
+}
+
+func TestUpdateAndDeleteLegacyMovieWithoutVersion(t *testing.T) {
+	h, repository := newTestMovieHandlers(t)
+	err := repository.Put(context.Background(), &MovieRecord{Id: "legacy", Title: "Legacy", Catalog: MovieCatalog})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	response := dispatchMovie(t, h, movieRequest{method: "PATCH", path: "/movies/legacy", body: `{"title":"Renamed","version":0}`})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("update status = %d (body %s)", response.StatusCode, response.Body)
+	}
+	var movie GetMovieResponse
+	if err := json.Unmarshal([]byte(response.Body), &movie); err != nil || movie.Version != 1 || movie.Title != "Renamed" {
+		t.Fatalf("updated movie = %+v, %v, want version 1", movie, err)
+	}
+	response = dispatchMovie(t, h, movieRequest{method: "PATCH", path: "/movies/legacy", body: `{"title":"Again","version":0}`})
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("second update with version 0 = %d, want %d", response.StatusCode, http.StatusConflict)
+	}
+
+	err = repository.Put(context.Background(), &MovieRecord{Id: "legacy-delete", Title: "Legacy", Catalog: MovieCatalog})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	response = dispatchMovie(t, h, movieRequest{method: "DELETE", path: "/movies/legacy-delete", query: map[string]string{"version": "0"}})
+	if response.StatusCode != http.StatusNoContent {
+		t.Errorf("delete status = %d (body %s)", response.StatusCode, response.Body)
+	}
+}
+
+func TestListRejectsCursorFromOtherQuery(t *testing.T) {
+	h, _ := newTestMovieHandlers(t)
+	_, rangeCursor := listMovieIds(t, h, map[string]string{"limit": "1", "from": "2000-01-01"})
+	_, scanCursor := listMovieIds(t, h, map[string]string{"limit": "1"})
+	if rangeCursor == nil || scanCursor == nil {
+		t.Fatal("expected cursors for the first pages")
+	}
+	tests := []struct {
+		name   string
+		cursor string
+		query  map[string]string
+	}{
+		{"range cursor with year", *rangeCursor, map[string]string{"year": "2000"}},
+		{"range cursor with title", *rangeCursor, map[string]string{"from": "2000-01-01", "title": "Movie a"}},
+		{"range cursor with order", *rangeCursor, map[string]string{"from": "2000-01-01", "order": "desc"}},
+		{"scan cursor with order", *scanCursor, map[string]string{"order": "asc"}},
+		{"scan cursor with date range", *scanCursor, map[string]string{"from": "2000-01-01"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := map[string]string{"exclusiveStartKey": test.cursor}
+			for key, value := range test.query {
+				query[key] = value
+			}
+			response := dispatchMovie(t, h, movieRequest{method: "GET", path: "/movies", query: query})
+			if response.StatusCode != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d (body %s)", response.StatusCode, http.StatusBadRequest, response.Body)
+			}
+		})
+	}
+	ids, _ := listMovieIds(t, h, map[string]string{"limit": "1", "from": "2000-01-01", "exclusiveStartKey": *rangeCursor})
+	if !slices.Equal(ids, []string{"b"}) {
+		t.Errorf("page 2 of the same query = %v, want [b]", ids)
+	}
+}
+
+func date(value string) *time.Time {
+	parsed, err := time.Parse(filterDateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return &parsed
+}
+
+func TestParseMovieFilter(t *testing.T) {
+	title := "Dune"
+	tests := []struct {
+		name   string
+		query  map[string]string
+		want   MovieFilter
+		errors []string
+	}{
+		{"defaults", map[string]string{}, MovieFilter{Limit: DefaultPageLimit}, nil},
+		{"title", map[string]string{"title": "Dune"}, MovieFilter{Title: &title, Limit: DefaultPageLimit}, nil},
+		{"year", map[string]string{"year": "1999"},
+			MovieFilter{From: date("1999-01-01"), To: date("1999-12-31"), Limit: DefaultPageLimit}, nil},
+		{"year narrowed by range", map[string]string{"year": "1999", "from": "1999-03-01", "to": "2005-01-01"},
+			MovieFilter{From: date("1999-03-01"), To: date("1999-12-31"), Limit: DefaultPageLimit}, nil},
+		{"range", map[string]string{"from": "1990-01-01", "to": "1999-12-31"},
+			MovieFilter{From: date("1990-01-01"), To: date("1999-12-31"), Limit: DefaultPageLimit}, nil},
+		{"ascending", map[string]string{"order": "asc"}, MovieFilter{Ordered: true, Limit: DefaultPageLimit}, nil},
+		{"descending", map[string]string{"order": "desc", "limit": "5"},
+			MovieFilter{Ordered: true, Descending: true, Limit: 5}, nil},
+		{"invalid values", map[string]string{"year": "nineteen", "from": "01/01/1990", "order": "up", "limit": "101"},
+			MovieFilter{}, []string{"from", "year", "limit", "order"}},
+		{"inverted range", map[string]string{"from": "2000-01-02", "to": "2000-01-01"}, MovieFilter{}, []string{"from"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, fieldErrors := parseMovieFilter(test.query)
+			fields := make([]string, len(fieldErrors))
+			for i, fieldError := range fieldErrors {
+				fields[i] = fieldError.Field
+			}
+			if !slices.Equal(fields, test.errors) {
+				t.Fatalf("field errors = %v, want %v", fields, test.errors)
+			}
+			if test.errors != nil {
+				return
+			}
+			if !equalPointers(filter.Title, test.want.Title) || !equalPointers(filter.From, test.want.From) ||
+				!equalPointers(filter.To, test.want.To) || filter.Ordered != test.want.Ordered ||
+				filter.Descending != test.want.Descending || filter.Limit != test.want.Limit {
+				t.Errorf("filter = %+v, want %+v", *filter, test.want)
+			}
+		})
+	}
+}
+
+func equalPointers[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	TitleIndex       = "title-index"
+	ReleaseDateIndex = "releaseDate-index"
+	MovieCatalog     = "movies"
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+	filterDateLayout = "2006-01-02"
+)
+
+var MovieNotFoundError = errors.New("movie not found")
+var MovieConflictError = errors.New("movie was modified by another request")
+
+type MovieFilter struct {
+	Title      *string
+	From       *time.Time
+	To         *time.Time
+	Descending bool
+	Ordered    bool
+	Limit      int32
+	StartKey   map[string]types.AttributeValue
+}
+
+type MoviePage struct {
+	Movies  []MovieRecord
+	LastKey map[string]types.AttributeValue
+}
+
+type MovieUpdate struct {
+	Title       *string
+	ReleaseDate *time.Time
+	Version     int64
+}
+
+type MovieRepository interface {
+	Get(ctx context.Context, id string) (*MovieRecord, error)
+	Put(ctx context.Context, record *MovieRecord) error
+	List(ctx context.Context, filter MovieFilter) (*MoviePage, error)
+	Update(ctx context.Context, id string, update MovieUpdate) (*MovieRecord, error)
+	Delete(ctx context.Context, id string, version *int64) error
+}
+
+type DynamoMovieRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+func newDynamoMovieRepository(client *dynamodb.Client, tableName string) *DynamoMovieRepository {
+	return &DynamoMovieRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+func primaryKey(id string) map[string]types.AttributeValue {
+	out, _ := attributevalue.MarshalMap(map[string]string{
+		"id": id,
+	})
+	return out
+}
+
+func conditionFailure(err error) error {
+	var conditionErr *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionErr) {
+		return err
+	}
+	if len(conditionErr.Item) == 0 {
+		return MovieNotFoundError
+	}
+	return MovieConflictError
+}
+
+func (r *DynamoMovieRepository) Get(ctx context.Context, id string) (*MovieRecord, error) {
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       primaryKey(id),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Item) == 0 {
+		return nil, MovieNotFoundError
+	}
+	var record MovieRecord
+	err = attributevalue.UnmarshalMap(out.Item, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *DynamoMovieRepository) Put(ctx context.Context, record *MovieRecord) error {
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           aws.String(r.tableName),
+		Item:                                item,
+		ConditionExpression:                 aws.String("attribute_not_exists(id)"),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	return conditionFailure(err)
+}
+
+// versionCondition matches the expected version. Items written before versioning have no version
+// attribute and read as version 0, so 0 only matches those.
+func versionCondition(version int64, names map[string]string, values map[string]types.AttributeValue) string {
+	names["#version"] = "version"
+	if version == 0 {
+		return "attribute_exists(id) AND attribute_not_exists(#version)"
+	}
+	values[":version"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	return "attribute_exists(id) AND #version = :version"
+}
+
+func (u *MovieUpdate) updateInput(tableName, id string) (*dynamodb.UpdateItemInput, error) {
+	values := map[string]types.AttributeValue{
+		":zero": &types.AttributeValueMemberN{Value: "0"},
+		":one":  &types.AttributeValueMemberN{Value: "1"},
+	}
+	names := map[string]string{}
+	condition := versionCondition(u.Version, names, values)
+	updates := []string{"#version = if_not_exists(#version, :zero) + :one"}
+	if u.Title != nil {
+		names["#title"] = "title"
+		values[":title"] = &types.AttributeValueMemberS{Value: *u.Title}
+		updates = append(updates, "#title = :title")
+	}
+	if u.ReleaseDate != nil {
+		releaseDate, err := attributevalue.Marshal(u.ReleaseDate.UTC())
+		if err != nil {
+			return nil, err
+		}
+		names["#releaseDate"] = "releaseDate"
+		values[":releaseDate"] = releaseDate
+		updates = append(updates, "#releaseDate = :releaseDate")
+	}
+	return &dynamodb.UpdateItemInput{
+		TableName:                           aws.String(tableName),
+		Key:                                 primaryKey(id),
+		UpdateExpression:                    aws.String("SET " + strings.Join(updates, ", ")),
+		ConditionExpression:                 aws.String(condition),
+		ExpressionAttributeNames:            names,
+		ExpressionAttributeValues:           values,
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}, nil
+}
+
+func (r *DynamoMovieRepository) Update(ctx context.Context, id string, update MovieUpdate) (*MovieRecord, error) {
+	input, err := update.updateInput(r.tableName, id)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.client.UpdateItem(ctx, input)
+	if err != nil {
+		return nil, conditionFailure(err)
+	}
+	var record MovieRecord
+	err = attributevalue.UnmarshalMap(out.Attributes, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func deleteInput(tableName, id string, version *int64) *dynamodb.DeleteItemInput {
+	input := &dynamodb.DeleteItemInput{
+		TableName:                           aws.String(tableName),
+		Key:                                 primaryKey(id),
+		ConditionExpression:                 aws.String("attribute_exists(id)"),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+	if version != nil {
+		names := map[string]string{}
+		values := map[string]types.AttributeValue{}
+		input.ConditionExpression = aws.String(versionCondition(*version, names, values))
+		input.ExpressionAttributeNames = names
+		if len(values) > 0 {
+			input.ExpressionAttributeValues = values
+		}
+	}
+	return input
+}
+
+func (r *DynamoMovieRepository) Delete(ctx context.Context, id string, version *int64) error {
+	_, err := r.client.DeleteItem(ctx, deleteInput(r.tableName, id, version))
+	return conditionFailure(err)
+}
+
+func (f *MovieFilter) hasFilter() bool {
+	return f.Title != nil || f.From != nil || f.To != nil || f.Ordered
+}
+
+func (f *MovieFilter) releaseDateBounds() (string, string) {
+	from := "0000-01-01"
+	to := "9999-12-31"
+	if f.From != nil {
+		from = f.From.Format(filterDateLayout)
+	}
+	if f.To != nil {
+		// Stored dates carry a time part, so the day after the inclusive end sorts after every item of that day.
+		to = f.To.AddDate(0, 0, 1).Format(filterDateLayout)
+	}
+	return from, to
+}
+
+func (f *MovieFilter) indexName() string {
+	if !f.hasFilter() {
+		return ""
+	}
+	if f.Title != nil {
+		return TitleIndex
+	}
+	return ReleaseDateIndex
+}
+
+// fingerprint identifies the index, key condition and order a start key belongs to. Limit is left out
+// as a cursor stays valid across page sizes.
+func (f *MovieFilter) fingerprint() string {
+	parts := []string{f.indexName()}
+	if f.hasFilter() {
+		from, to := f.releaseDateBounds()
+		parts = append(parts, from, to, strconv.FormatBool(f.Descending))
+	}
+	if f.Title != nil {
+		parts = append(parts, *f.Title)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+func (f *MovieFilter) queryInput(tableName string) *dynamodb.QueryInput {
+	from, to := f.releaseDateBounds()
+	names := map[string]string{
+		"#releaseDate": "releaseDate",
+	}
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberS{Value: from},
+		":to":   &types.AttributeValueMemberS{Value: to},
+	}
+	input := &dynamodb.QueryInput{
+		TableName:        aws.String(tableName),
+		ScanIndexForward: aws.Bool(!f.Descending),
+	}
+	input.IndexName = aws.String(f.indexName())
+	if f.Title != nil {
+		names["#title"] = "title"
+		values[":title"] = &types.AttributeValueMemberS{Value: *f.Title}
+		input.KeyConditionExpression = aws.String("#title = :title AND #releaseDate BETWEEN :from AND :to")
+	} else {
+		names["#catalog"] = "catalog"
+		values[":catalog"] = &types.AttributeValueMemberS{Value: MovieCatalog}
+		input.KeyConditionExpression = aws.String("#catalog = :catalog AND #releaseDate BETWEEN :from AND :to")
+	}
+	input.ExpressionAttributeNames = names
+	input.ExpressionAttributeValues = values
+	return input
+}
+
+func (r *DynamoMovieRepository) page(ctx context.Context,
+	filter MovieFilter,
+	limit int32,
+	startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+	if !filter.hasFilter() {
+		out, err := r.client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(r.tableName),
+			Limit:             aws.Int32(limit),
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return out.Items, out.LastEvaluatedKey, nil
+	}
+	input := filter.queryInput(r.tableName)
+	input.Limit = aws.Int32(limit)
+	input.ExclusiveStartKey = startKey
+	out, err := r.client.Query(ctx, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out.Items, out.LastEvaluatedKey, nil
+}
+
+func (r *DynamoMovieRepository) List(ctx context.Context, filter MovieFilter) (*MoviePage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	result := &MoviePage{
+		Movies: make([]MovieRecord, 0, limit),
+	}
+	startKey := filter.StartKey
+	// A single call stops at 1MB of data, keep reading until the page is full so every page honours the limit.
+	for {
+		items, lastKey, err := r.page(ctx, filter, limit-int32(len(result.Movies)), startKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			var record MovieRecord
+			if err = attributevalue.UnmarshalMap(item, &record); err != nil {
+				return nil, err
+			}
+			result.Movies = append(result.Movies, record)
+		}
+		result.LastKey = lastKey
+		if lastKey == nil || int32(len(result.Movies)) >= limit {
+			return result, nil
+		}
+		startKey = lastKey
+	}
+}
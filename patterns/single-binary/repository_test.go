@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestUpdateInputVersionCondition(t *testing.T) {
+	title := "Renamed"
+	tests := []struct {
+		name      string
+		version   int64
+		condition string
+	}{
+		{"legacy item", 0, "attribute_exists(id) AND attribute_not_exists(#version)"},
+		{"versioned item", 3, "attribute_exists(id) AND #version = :version"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			update := MovieUpdate{Title: &title, Version: test.version}
+			input, err := update.updateInput("movies", "movie-1")
+			if err != nil {
+				t.Fatalf("updateInput: %v", err)
+			}
+			if got := aws.ToString(input.ConditionExpression); got != test.condition {
+				t.Errorf("condition = %q, want %q", got, test.condition)
+			}
+			if got := aws.ToString(input.UpdateExpression); got != "SET #version = if_not_exists(#version, :zero) + :one, #title = :title" {
+				t.Errorf("update = %q", got)
+			}
+			version, ok := input.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN)
+			if test.version == 0 && ok {
+				t.Errorf("unexpected :version value %q for a legacy item", version.Value)
+			}
+			if test.version != 0 && (!ok || version.Value != "3") {
+				t.Errorf(":version = %v, want 3", input.ExpressionAttributeValues[":version"])
+			}
+		})
+	}
+}
+
+func TestDeleteInputVersionCondition(t *testing.T) {
+	legacy := int64(0)
+	versioned := int64(2)
+	tests := []struct {
+		name      string
+		version   *int64
+		condition string
+		values    int
+	}{
+		{"unconditional", nil, "attribute_exists(id)", 0},
+		{"legacy item", &legacy, "attribute_exists(id) AND attribute_not_exists(#version)", 0},
+		{"versioned item", &versioned, "attribute_exists(id) AND #version = :version", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := deleteInput("movies", "movie-1", test.version)
+			if got := aws.ToString(input.ConditionExpression); got != test.condition {
+				t.Errorf("condition = %q, want %q", got, test.condition)
+			}
+			if len(input.ExpressionAttributeValues) != test.values {
+				t.Errorf("values = %v, want %d entries", input.ExpressionAttributeValues, test.values)
+			}
+		})
+	}
+}
+
+func TestQueryInput(t *testing.T) {
+	title := "Dune"
+	tests := []struct {
+		name      string
+		filter    MovieFilter
+		index     string
+		condition string
+		from      string
+		to        string
+		forward   bool
+	}{
+		{"title", MovieFilter{Title: &title}, TitleIndex,
+			"#title = :title AND #releaseDate BETWEEN :from AND :to", "0000-01-01", "9999-12-31", true},
+		{"year", MovieFilter{From: date("1999-01-01"), To: date("1999-12-31")}, ReleaseDateIndex,
+			"#catalog = :catalog AND #releaseDate BETWEEN :from AND :to", "1999-01-01", "2000-01-01", true},
+		{"title within range", MovieFilter{Title: &title, From: date("1984-01-01"), To: date("2021-10-22")}, TitleIndex,
+			"#title = :title AND #releaseDate BETWEEN :from AND :to", "1984-01-01", "2021-10-23", true},
+		{"descending", MovieFilter{Ordered: true, Descending: true}, ReleaseDateIndex,
+			"#catalog = :catalog AND #releaseDate BETWEEN :from AND :to", "0000-01-01", "9999-12-31", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.filter.hasFilter() {
+				t.Fatal("filter would scan instead of query")
+			}
+			input := test.filter.queryInput("movies")
+			if got := aws.ToString(input.IndexName); got != test.index {
+				t.Errorf("index = %q, want %q", got, test.index)
+			}
+			if got := aws.ToString(input.KeyConditionExpression); got != test.condition {
+				t.Errorf("key condition = %q, want %q", got, test.condition)
+			}
+			from := input.ExpressionAttributeValues[":from"].(*types.AttributeValueMemberS).Value
+			to := input.ExpressionAttributeValues[":to"].(*types.AttributeValueMemberS).Value
+			if from != test.from || to != test.to {
+				t.Errorf("bounds = %s..%s, want %s..%s", from, to, test.from, test.to)
+			}
+			if got := aws.ToBool(input.ScanIndexForward); got != test.forward {
+				t.Errorf("ScanIndexForward = %v, want %v", got, test.forward)
+			}
+		})
+	}
+}
+
+func TestUnfilteredListScans(t *testing.T) {
+	filter, _ := parseMovieFilter(map[string]string{"limit": "10"})
+	if filter.hasFilter() {
+		t.Error("a listing without title, range or order should scan the table")
+	}
+}
+
+func TestReleaseDateBoundsIncludeLastDay(t *testing.T) {
+	filter := MovieFilter{To: date("1999-12-31")}
+	_, to := filter.releaseDateBounds()
+	stored := time.Date(1999, time.December, 31, 23, 59, 0, 0, time.UTC).Format(time.RFC3339)
+	if !(stored < to) {
+		t.Errorf("stored date %s not below upper bound %s", stored, to)
+	}
+	next := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	if next < to {
+		t.Errorf("next day %s is below upper bound %s", next, to)
+	}
+}
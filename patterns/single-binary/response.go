@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeJson   = "application/json"
+	ContentTypeCsv    = "text/csv"
+	ContentTypeNdjson = "application/x-ndjson"
+	ContentTypeText   = "text/plain"
+	NextCursorHeader  = "X-Next-Cursor"
+)
+
+type CsvRecord interface {
+	CsvHeader() []string
+	CsvRow() []string
+}
+
+type acceptedType struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []acceptedType {
+	var accepted []acceptedType
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		accepted = append(accepted, acceptedType{mediaType: mediaType, quality: quality})
+	}
+	return accepted
+}
+
+// mediaTypeSpecificity ranks how closely pattern matches mediaType, -1 when it does not match at all.
+func mediaTypeSpecificity(pattern, mediaType string) int {
+	if pattern == mediaType {
+		return 2
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && prefix != "*" && strings.HasPrefix(mediaType, prefix+"/") {
+		return 1
+	}
+	if pattern == "*/*" {
+		return 0
+	}
+	return -1
+}
+
+// acceptQuality returns the q-value of the most specific range matching mediaType, so an explicit
+// text/csv;q=0 wins over */*.
+func acceptQuality(accepted []acceptedType, mediaType string) float64 {
+	specificity := -1
+	q := 0.0
+	for _, a := range accepted {
+		if s := mediaTypeSpecificity(a.mediaType, mediaType); s > specificity {
+			specificity = s
+			q = a.quality
+		}
+	}
+	return q
+}
+
+func negotiate(request *Request, offered ...string) (string, bool) {
+	accept := getHeader(request.Headers, "Accept")
+	if accept == nil || strings.TrimSpace(*accept) == "" {
+		return offered[0], true
+	}
+	accepted := parseAccept(*accept)
+	best := ""
+	bestQuality := 0.0
+	for _, candidate := range offered {
+		if q := acceptQuality(accepted, candidate); q > bestQuality {
+			best = candidate
+			bestQuality = q
+		}
+	}
+	return best, best != ""
+}
+
+func isTextual(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == ContentTypeJson ||
+		mediaType == ContentTypeNdjson ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/xml"
+}
+
+func BodyResponse(statusCode int, contentType string, body []byte) Response {
+	response := Response{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type": contentType,
+		},
+	}
+	if isTextual(contentType) {
+		response.Body = string(body)
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(body)
+		response.IsBase64Encoded = true
+	}
+	return response
+}
+
+func TextResponse(statusCode int, message string) Response {
+	return BodyResponse(statusCode, ContentTypeText+"; charset=utf-8", []byte(message))
+}
+
+func JsonResponse(statusCode int, body any) Response {
+	responseBody, err := json.Marshal(body)
+	if err != nil {
+		return InternalServerError(err)
+	}
+	return BodyResponse(statusCode, ContentTypeJson, responseBody)
+}
+
+func NotAcceptable(offered ...string) Response {
+	return TextResponse(http.StatusNotAcceptable, "supported media types: "+strings.Join(offered, ", "))
+}
+
+func ListResponse[T CsvRecord](request *Request, items []T, envelope any, cursor *string) Response {
+	contentType, ok := negotiate(request, ContentTypeJson, ContentTypeCsv, ContentTypeNdjson)
+	if !ok {
+		return NotAcceptable(ContentTypeJson, ContentTypeCsv, ContentTypeNdjson)
+	}
+	var response Response
+	switch contentType {
+	case ContentTypeCsv:
+		var buffer bytes.Buffer
+		writer := csv.NewWriter(&buffer)
+		var header T
+		_ = writer.Write(header.CsvHeader())
+		for _, item := range items {
+			_ = writer.Write(item.CsvRow())
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return InternalServerError(err)
+		}
+		response = BodyResponse(http.StatusOK, ContentTypeCsv+"; charset=utf-8", buffer.Bytes())
+	case ContentTypeNdjson:
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return InternalServerError(err)
+			}
+		}
+		response = BodyResponse(http.StatusOK, ContentTypeNdjson, buffer.Bytes())
+	default:
+		response = JsonResponse(http.StatusOK, envelope)
+	}
+	response.Headers["Vary"] = "Accept"
+	if cursor != nil {
+		response.Headers[NextCursorHeader] = *cursor
+	}
+	return response
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	offered := []string{ContentTypeJson, ContentTypeCsv, ContentTypeNdjson}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ContentTypeJson},
+		{"text/csv", ContentTypeCsv},
+		{"application/x-ndjson, text/csv;q=0.5", ContentTypeNdjson},
+		{"text/*", ContentTypeCsv},
+		{"*/*", ContentTypeJson},
+		{"text/csv;q=0.2, */*;q=0.1", ContentTypeCsv},
+		{"text/csv;q=0, */*", ContentTypeJson},
+		{"application/json;q=0, application/*;q=0.5, text/csv;q=0.4", ContentTypeNdjson},
+		{"text/csv;q=0, text/*", ""},
+		{"image/png", ""},
+	}
+	for _, test := range tests {
+		request := Request{Headers: map[string]string{"Accept": test.accept}}
+		got, ok := negotiate(&request, offered...)
+		if got != test.want || ok != (test.want != "") {
+			t.Errorf("negotiate(%q) = %q, %v, want %q", test.accept, got, ok, test.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type route struct {
+	key      string
+	method   string
+	segments []string
+	handler  HandlerFunc
+}
+
+type Router struct {
+	routes []route
+}
+
+func newRouter() *Router {
+	return &Router{}
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isPathParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func (r *Router) add(routeKey string, handler HandlerFunc) {
+	method, path, ok := strings.Cut(routeKey, " ")
+	if !ok {
+		panic(fmt.Sprintf("invalid route key %s", routeKey))
+	}
+	r.routes = append(r.routes, route{
+		key:      routeKey,
+		method:   method,
+		segments: splitPath(path),
+		handler:  handler,
+	})
+}
+
+func (rt *route) matchPath(path string) (map[string]string, bool) {
+	segments := splitPath(path)
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range rt.segments {
+		if isPathParam(segment) {
+			value, err := url.PathUnescape(segments[i])
+			if err != nil || value == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = value
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (r *Router) dispatch(ctx context.Context, request Request) (Response, error) {
+	for _, rt := range r.routes {
+		if rt.key == request.RouteKey {
+			return rt.handler(ctx, request)
+		}
+	}
+	method := request.RequestContext.HTTP.Method
+	pathMatched := false
+	for _, rt := range r.routes {
+		params, ok := rt.matchPath(request.RawPath)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if rt.method != method && rt.method != "ANY" {
+			continue
+		}
+		slog.Info("Route matched", "route", rt.key, "path", request.RawPath)
+		if request.PathParameters == nil {
+			request.PathParameters = make(map[string]string)
+		}
+		for key, value := range params {
+			request.PathParameters[key] = value
+		}
+		return rt.handler(ctx, request)
+	}
+	if pathMatched {
+		return Response{StatusCode: http.StatusMethodNotAllowed}, nil
+	}
+	slog.Info("Route not matched", "method", method, "path", request.RawPath)
+	return Response{StatusCode: http.StatusNotFound}, nil
+}
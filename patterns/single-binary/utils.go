@@ -8,18 +8,13 @@ import (
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 type Request events.APIGatewayV2HTTPRequest
 type Response events.APIGatewayV2HTTPResponse
 
 func InternalServerError(err error) Response {
-	return Response{
-		StatusCode: http.StatusInternalServerError,
-		Body:       err.Error(),
-	}
+	return TextResponse(http.StatusInternalServerError, err.Error())
 }
 
 func getOptional[K comparable, V any](m map[K]V, key K) *V {
@@ -47,18 +42,6 @@ func getHeader(headers map[string]string, headerKey string) *string {
 	return nil
 }
 
-func convertLastEvaluatedKey(m map[string]types.AttributeValue) (*string, error) {
-	if m != nil {
-		out, err := attributevalue.MarshalMapJSON(m)
-		if err != nil {
-			return nil, nil
-		}
-		result := base64.RawURLEncoding.EncodeToString(out)
-		return &result, nil
-	}
-	return nil, nil
-}
-
 func unmarshallBody[T any](request *Request) (*T, error) {
 	var result T
 	if request.IsBase64Encoded {
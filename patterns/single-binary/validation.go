@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ValidateTag        = "validate"
+	ProblemContentType = "application/problem+json"
+)
+
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type Validator interface {
+	Validate() []FieldError
+}
+
+func ProblemResponse(status int, detail string, fieldErrors []FieldError) Response {
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Errors: fieldErrors,
+	}
+	body, err := json.Marshal(&problem)
+	if err != nil {
+		return InternalServerError(err)
+	}
+	return Response{
+		StatusCode: status,
+		Headers: map[string]string{
+			"Content-Type": ProblemContentType,
+		},
+		Body: string(body),
+	}
+}
+
+type rule struct {
+	name  string
+	check func(field reflect.Value) string
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+}
+
+type bodyRules []fieldRules
+
+// compileRules parses the validate tags of t once, so a malformed or unsupported rule fails at registration
+// rather than during a request.
+func compileRules(t reflect.Type) (bodyRules, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	var compiled bodyRules
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup(ValidateTag)
+		if !ok || !f.IsExported() {
+			continue
+		}
+		field := fieldRules{index: i, name: fieldName(f)}
+		for _, tagRule := range strings.Split(tag, ",") {
+			compiledRule, err := compileRule(f.Type, tagRule)
+			if err != nil {
+				return nil, fmt.Errorf("field %s of %s: %w", f.Name, t, err)
+			}
+			field.rules = append(field.rules, compiledRule)
+		}
+		compiled = append(compiled, field)
+	}
+	return compiled, nil
+}
+
+func compileRule(fieldType reflect.Type, tagRule string) (rule, error) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(tagRule), "=")
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch name {
+	case "required":
+		return rule{name: name, check: func(field reflect.Value) string {
+			if field.IsZero() {
+				return "is required"
+			}
+			return ""
+		}}, nil
+	case "min", "max":
+		bound, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid %s rule argument %q", name, arg)
+		}
+		var length func(field reflect.Value) int64
+		var unit string
+		switch fieldType.Kind() {
+		case reflect.String:
+			length = func(field reflect.Value) int64 { return int64(utf8.RuneCountInString(field.String())) }
+			unit = " characters"
+		case reflect.Slice, reflect.Map:
+			length = func(field reflect.Value) int64 { return int64(field.Len()) }
+			unit = " items"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			length = reflect.Value.Int
+		default:
+			return rule{}, fmt.Errorf("%s rule not supported for %s", name, fieldType)
+		}
+		return rule{name: name, check: func(field reflect.Value) string {
+			actual := length(field)
+			if name == "min" && actual < bound {
+				return fmt.Sprintf("must be at least %d%s", bound, unit)
+			}
+			if name == "max" && actual > bound {
+				return fmt.Sprintf("must be at most %d%s", bound, unit)
+			}
+			return ""
+		}}, nil
+	case "after", "before":
+		if fieldType != reflect.TypeFor[time.Time]() {
+			return rule{}, fmt.Errorf("%s rule not supported for %s", name, fieldType)
+		}
+		bound, err := time.Parse(filterDateLayout, arg)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid %s rule argument %q", name, arg)
+		}
+		return rule{name: name, check: func(field reflect.Value) string {
+			date := field.Interface().(time.Time)
+			if name == "after" && date.Before(bound) {
+				return fmt.Sprintf("must not be before %s", arg)
+			}
+			if name == "before" && date.After(bound) {
+				return fmt.Sprintf("must not be after %s", arg)
+			}
+			return ""
+		}}, nil
+	}
+	return rule{}, fmt.Errorf("unknown validation rule %q", name)
+}
+
+func mustCompileRules[T any]() bodyRules {
+	rules, err := compileRules(reflect.TypeFor[T]())
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+func isJsonContentType(contentType *string) bool {
+	if contentType == nil {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(*contentType)
+	return err == nil && mediaType == ContentTypeJson
+}
+
+func decodeAndValidate[T any](request *Request, rules bodyRules) (*T, *Response) {
+	if !isJsonContentType(getHeader(request.Headers, "Content-Type")) {
+		response := ProblemResponse(http.StatusBadRequest, "application/json Content-Type expected", nil)
+		return nil, &response
+	}
+	body, err := unmarshallBody[T](request)
+	if err != nil {
+		slog.Info("Malformed request body", "error", err.Error())
+		response := ProblemResponse(http.StatusBadRequest, "malformed request body", nil)
+		return nil, &response
+	}
+	fieldErrors := validate(body, rules)
+	if len(fieldErrors) > 0 {
+		response := ProblemResponse(http.StatusBadRequest, "request validation failed", fieldErrors)
+		return nil, &response
+	}
+	return body, nil
+}
+
+func validate(value any, rules bodyRules) []FieldError {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var fieldErrors []FieldError
+	for _, field := range rules {
+		if message := checkField(v.Field(field.index), field.rules); message != "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.name, Message: message})
+		}
+	}
+	if !v.CanAddr() {
+		return fieldErrors
+	}
+	if validator, ok := v.Addr().Interface().(Validator); ok {
+		fieldErrors = append(fieldErrors, validator.Validate()...)
+	}
+	return fieldErrors
+}
+
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+// checkField returns the message of the first failing rule.
+func checkField(field reflect.Value, rules []rule) string {
+	pointer := field.Kind() == reflect.Ptr
+	if pointer {
+		if field.IsNil() {
+			for _, r := range rules {
+				if r.name == "required" {
+					return "is required"
+				}
+			}
+			return ""
+		}
+		field = field.Elem()
+	}
+	for _, r := range rules {
+		// A pointer marks presence, so a non-nil pointer to a zero value still satisfies required.
+		if r.name == "required" && pointer {
+			continue
+		}
+		if message := r.check(field); message != "" {
+			return message
+		}
+	}
+	return ""
+}
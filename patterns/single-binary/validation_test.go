@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileRulesRejectsInvalidTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"unknown rule", struct {
+			Title string `validate:"email"`
+		}{}, "unknown validation rule"},
+		{"invalid bound", struct {
+			Title string `validate:"max=ten"`
+		}{}, "invalid max rule argument"},
+		{"unsupported kind", struct {
+			Done bool `validate:"min=1"`
+		}{}, "min rule not supported"},
+		{"invalid date", struct {
+			Date time.Time `validate:"after=yesterday"`
+		}{}, "invalid after rule argument"},
+		{"date rule on string", struct {
+			Date string `validate:"before=2000-01-01"`
+		}{}, "before rule not supported"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := compileRules(reflect.TypeOf(test.value))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("err = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestRegisterBodyRoutePanicsOnInvalidRules(t *testing.T) {
+	type invalidRequest struct {
+		Title string `validate:"unknown"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registration to panic")
+		}
+	}()
+	RegisterBodyRoute(newTestHandlers(), "Invalid", "POST /invalid",
+		func(ctx context.Context, request Request, body *invalidRequest) (Response, error) {
+			return Response{StatusCode: http.StatusOK}, nil
+		})
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	rules := mustCompileRules[SaveMovieRequest]()
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		detail      string
+	}{
+		{"json with charset", "application/json; charset=utf-8", `{"title":"Dune","releaseDate":"2021-10-22T00:00:00Z"}`, ""},
+		{"wrong content type", "text/plain", `{}`, "application/json Content-Type expected"},
+		{"malformed body", ContentTypeJson, `{"title":`, "malformed request body"},
+		{"invalid body", ContentTypeJson, `{"title":""}`, "request validation failed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := Request{Headers: map[string]string{"Content-Type": test.contentType}, Body: test.body}
+			_, problem := decodeAndValidate[SaveMovieRequest](&request, rules)
+			if test.detail == "" {
+				if problem != nil {
+					t.Fatalf("unexpected problem %s", problem.Body)
+				}
+				return
+			}
+			if problem == nil {
+				t.Fatal("expected problem")
+			}
+			if !strings.Contains(problem.Body, `"detail":"`+test.detail+`"`) {
+				t.Errorf("body = %s, want detail %q", problem.Body, test.detail)
+			}
+		})
+	}
+}